@@ -4,6 +4,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"path/filepath"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"github.com/elimisteve/do_reminder/remind"
+	"github.com/elimisteve/do_reminder/twilhelp"
 )
 
 var regexTime = regexp.MustCompile(`^\d?\d:\d\d$`)
@@ -68,9 +70,9 @@ func main() {
 
 			sleep := rem.NextRun.Sub(now)
 			log.Printf("Reminder `%s` will run in %s\n", rem.Description, sleep)
-			time.Sleep(sleep)
+			remind.Sleep(sleep)
 
-			if err := rem.SendSMS(); err != nil {
+			if _, err := rem.Send(context.Background(), twilhelp.DefaultSender); err != nil {
 				log.Printf("Error sending Reminder `%v` to %s: %v\n",
 					rem.Description, rem.Recipient, err)
 				return
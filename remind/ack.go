@@ -0,0 +1,189 @@
+package remind
+
+import (
+	"container/heap"
+	"errors"
+	"time"
+)
+
+const (
+	// DefaultNagEvery is how often a reminder that must be
+	// acknowledged is sent again, unless its recipient says otherwise
+	DefaultNagEvery = 15 * time.Minute
+
+	// MinNagEvery is the most often a reminder may be sent again
+	MinNagEvery = 5 * time.Minute
+
+	// DefaultMaxNags is how many times a run is sent again before
+	// giving up on it being acknowledged, unless the reminder says
+	DefaultMaxNags = 4
+
+	// MaxHistory is how many past runs a reminder keeps the History of
+	MaxHistory = 50
+)
+
+var ErrNothingPending = errors.New("Reminder isn't waiting for you to say" +
+	" it's done")
+
+// Occurrence is a run of a reminder that must be acknowledged
+type Occurrence struct {
+	Nominal time.Time // The run
+	Sent    time.Time
+
+	// Nags is how many times it's been sent again; NextNag, when it
+	// will be next, or zero once it won't be
+	Nags    int       `json:",omitempty"`
+	NextNag time.Time `json:",omitempty"`
+
+	// Acked is when its recipient said it was done; zero if they
+	// haven't (yet)
+	Acked time.Time `json:",omitempty"`
+
+	// EscalateAt is when the reminder's EscalateTo are told it wasn't
+	// done, or zero once they won't be; Escalated, when they were
+	EscalateAt  time.Time `json:",omitempty"`
+	Escalated   time.Time `json:",omitempty"`
+	EscalatedTo []string  `json:",omitempty"`
+}
+
+// MustAck reports whether r's runs must be acknowledged
+func (r *Reminder) MustAck() bool {
+	return r.NagEvery != 0 || r.escalates()
+}
+
+// maxNags returns how many times each of r's runs is sent again
+func (r *Reminder) maxNags() int {
+	if r.MaxNags > 0 {
+		return r.MaxNags
+	}
+	return DefaultMaxNags
+}
+
+// startOccurrence records that r's next run was just sent and must be
+// acknowledged, giving up on the previous one if it wasn't
+func (r *Reminder) startOccurrence() {
+	if r.Pending != nil {
+		r.closeOccurrence()
+	}
+	now := Now()
+	r.Pending = &Occurrence{
+		Nominal: r.Nominal,
+		Sent:    now,
+	}
+	if r.NagEvery != 0 {
+		r.Pending.NextNag = now.Add(r.NagEvery)
+	}
+	if r.escalates() {
+		r.Pending.EscalateAt = now.Add(r.escalateAfter())
+	}
+}
+
+// nagged records that r's pending run was just sent again, giving up
+// on it once it's been sent MaxNags times
+func (r *Reminder) nagged() {
+	p := r.Pending
+	p.Nags++
+	if p.Nags >= r.maxNags() {
+		p.NextNag = time.Time{}
+		r.settle()
+		return
+	}
+	p.NextNag = Now().Add(r.NagEvery)
+}
+
+// settle moves r's pending run to its History once nothing more will
+// be sent about it
+func (r *Reminder) settle() {
+	if p := r.Pending; p.NextNag.IsZero() && p.EscalateAt.IsZero() {
+		r.closeOccurrence()
+	}
+}
+
+// closeOccurrence moves r's pending run to its History
+func (r *Reminder) closeOccurrence() {
+	p := r.Pending
+	p.NextNag = time.Time{}
+	p.EscalateAt = time.Time{}
+	r.Pending = nil
+
+	r.History = append(r.History, p)
+	if len(r.History) > MaxHistory {
+		r.History = r.History[len(r.History)-MaxHistory:]
+	}
+}
+
+// ack records that r's pending run, or if it's no longer waiting, its
+// last unacknowledged one, is done. It returns which, or nil if
+// there's nothing to acknowledge.
+func (r *Reminder) ack() *Occurrence {
+	if p := r.Pending; p != nil {
+		p.Acked = Now()
+		r.closeOccurrence()
+		return p
+	}
+
+	// Done after giving up on being told so still counts
+	if n := len(r.History); n > 0 && r.History[n-1].Acked.IsZero() {
+		last := r.History[n-1]
+		last.Acked = Now()
+		return last
+	}
+
+	return nil
+}
+
+// Ack records that owner did what the reminder with ID id reminded
+// them to, so its latest run stops being sent again. It returns
+// ErrNothingPending if that run was already acknowledged.
+func (s *Scheduler) Ack(owner string, id uint64) (*Occurrence, error) {
+	occ, sn, scheduled, err := s.ackScheduled(owner, id)
+	if err != nil {
+		return nil, err
+	}
+	if scheduled {
+		return occ, s.save(sn)
+	}
+
+	r, err := GetOwnReminder(s.db, owner, id)
+	if err != nil {
+		return nil, err
+	}
+	if occ = r.ack(); occ == nil {
+		return nil, ErrNothingPending
+	}
+	return occ, r.Update(s.db)
+}
+
+// ackScheduled is Ack for the reminder with ID id if it's scheduled,
+// short of saving it
+func (s *Scheduler) ackScheduled(owner string, id uint64) (occ *Occurrence, sn *snapshot, scheduled bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, scheduled := s.byID[id]
+	if !scheduled {
+		return nil, nil, false, nil
+	}
+	r := e.r
+	if r.Recipient != owner {
+		return nil, nil, true, ErrNotOwner
+	}
+
+	occ = r.ack()
+	if occ == nil {
+		return nil, nil, true, ErrNothingPending
+	}
+
+	// Otherwise send takes care of it
+	if e.index >= 0 {
+		if _, _, ok := r.nextSend(); ok {
+			heap.Fix(&s.queue, e.index)
+		} else {
+			heap.Remove(&s.queue, e.index)
+			delete(s.byID, id)
+		}
+		s.notify()
+	}
+
+	return occ, s.snapshot(e), true, nil
+}
@@ -0,0 +1,121 @@
+package remind
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedulerNags(t *testing.T) {
+	s, clk, sent := newTestScheduler(t)
+	start := time.Date(2026, 6, 1, 9, 0, 0, 0, LosAngeles)
+	r := &Reminder{NextRun: start, Period: 24 * time.Hour,
+		NagEvery: 10 * time.Minute, MaxNags: 2}
+	addTestReminder(t, s, r)
+
+	_, err := s.Ack(r.Recipient, r.ID)
+	assert.Equal(t, ErrNothingPending, err, "Not sent yet")
+
+	clk.Advance(time.Hour)
+	assert.Equal(t, 1, s.sendDue())
+	assert.Equal(t, `Reminder 1: Test (reply "done 1" when done)`,
+		sent.Last().Body)
+	if assert.NotNil(t, r.Pending) {
+		assert.Equal(t, start, r.Pending.Nominal)
+	}
+
+	// Sent again every 10 minutes, without changing its schedule...
+	for i := 1; i <= 2; i++ {
+		_, wait := s.popDue()
+		assert.Equal(t, 10*time.Minute, wait)
+		clk.Advance(wait)
+		assert.Equal(t, 1, s.sendDue())
+		assert.Len(t, sent.Sent(), 1+i)
+	}
+	assert.Equal(t, 1, r.Runs)
+
+	// ...until it's been sent MaxNags times
+	assert.Nil(t, r.Pending)
+	if assert.Len(t, r.History, 1) {
+		assert.Equal(t, 2, r.History[0].Nags)
+		assert.True(t, r.History[0].Acked.IsZero())
+	}
+	_, wait := s.popDue()
+	assert.Equal(t, 24*time.Hour-20*time.Minute, wait)
+
+	// Still counts if it's done late
+	occ, err := s.Ack(r.Recipient, r.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, Now(), occ.Acked)
+	}
+	_, err = s.Ack(r.Recipient, r.ID)
+	assert.Equal(t, ErrNothingPending, err)
+
+	// Done before being sent again
+	clk.Advance(wait)
+	assert.Equal(t, 1, s.sendDue())
+	_, err = s.Ack("+15555550199", r.ID)
+	assert.Equal(t, ErrNotOwner, err)
+	_, err = s.Ack(r.Recipient, r.ID)
+	assert.NoError(t, err)
+
+	assert.Nil(t, r.Pending)
+	if assert.Len(t, r.History, 2) {
+		assert.Equal(t, start.AddDate(0, 0, 1), r.History[1].Nominal)
+		assert.Equal(t, 0, r.History[1].Nags)
+		assert.Equal(t, Now(), r.History[1].Acked)
+	}
+	_, wait = s.popDue()
+	assert.Equal(t, 24*time.Hour, wait)
+
+	saved, err := GetReminder(s.db, r.ID)
+	if assert.NoError(t, err) {
+		assert.Len(t, saved.History, 2)
+	}
+}
+
+func TestNagOneShot(t *testing.T) {
+	s, clk, sent := newTestScheduler(t)
+	r := &Reminder{NextRun: Now().Add(time.Hour), NagEvery: 15 * time.Minute}
+	addTestReminder(t, s, r)
+
+	clk.Advance(time.Hour)
+	assert.Equal(t, 1, s.sendDue())
+	assert.True(t, r.Completed)
+	assert.Equal(t, 1, s.Len(), "Sent again until it's done")
+
+	// Still sent again after a restart
+	s = NewScheduler(s.db, sent, 1)
+	rems, err := GetAllReminders(s.db)
+	if !assert.NoError(t, err) {
+		return
+	}
+	s.Schedule(rems)
+	assert.Equal(t, 1, s.Len())
+
+	clk.Advance(15 * time.Minute)
+	assert.Equal(t, 1, s.sendDue())
+	assert.Len(t, sent.Sent(), 2)
+	assert.True(t, strings.HasSuffix(sent.Last().Body, "when done)"))
+
+	_, err = s.Ack(r.Recipient, r.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, s.Len())
+
+	saved, err := GetReminder(s.db, r.ID)
+	if assert.NoError(t, err) && assert.Len(t, saved.History, 1) {
+		assert.Nil(t, saved.Pending)
+		assert.Equal(t, 1, saved.History[0].Nags)
+		assert.Equal(t, Now(), saved.History[0].Acked.In(LosAngeles))
+	}
+}
+
+func TestNagEveryMin(t *testing.T) {
+	s, _, _ := newTestScheduler(t)
+	r := &Reminder{NextRun: Now().Add(time.Hour), NagEvery: time.Minute}
+	r.Recipient = "+15555550100"
+	assert.NoError(t, r.Save(s.db))
+	assert.Error(t, s.Add(r))
+}
@@ -0,0 +1,159 @@
+package remind
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and makes timers. Everything in this package
+// uses the one set with SetClock, so tests can control time with a
+// FakeClock.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is like *time.Timer
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+var clock Clock = realClock{}
+
+// SetClock makes this package use c, returning the Clock it used
+// before
+func SetClock(c Clock) Clock {
+	prev := clock
+	clock = c
+	return prev
+}
+
+// Sleep pauses the current goroutine for d, according to the Clock
+func Sleep(d time.Duration) {
+	<-clock.NewTimer(d).C()
+}
+
+// realClock is the system clock
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+// FakeClock is a Clock whose time only changes when told to, for
+// tests
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer // Armed timers
+	changed *sync.Cond   // Signaled when a timer is armed
+}
+
+// NewFakeClock returns a FakeClock set to now
+func NewFakeClock(now time.Time) *FakeClock {
+	f := &FakeClock{now: now}
+	f.changed = sync.NewCond(&f.mu)
+	return f
+}
+
+func (f *FakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *FakeClock) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves f's time forward by d, firing the timers due by then
+// in order
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = f.now.Add(d)
+
+	sort.Slice(f.timers, func(i, j int) bool {
+		return f.timers[i].deadline.Before(f.timers[j].deadline)
+	})
+	for len(f.timers) > 0 && !f.timers[0].deadline.After(f.now) {
+		t := f.timers[0]
+		f.timers = f.timers[1:]
+		t.fire(f.now)
+	}
+}
+
+// BlockUntil waits until n timers are armed, e.g. so a test knows the
+// code it's testing is waiting for a certain time before advancing to
+// it
+func (f *FakeClock) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for len(f.timers) < n {
+		f.changed.Wait()
+	}
+}
+
+// disarm removes t from f's armed timers, reporting whether it was
+// armed. f.mu must be held.
+func (f *FakeClock) disarm(t *fakeTimer) bool {
+	for i, t2 := range f.timers {
+		if t2 == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	c        chan time.Time
+	deadline time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.disarm(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	f := t.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	armed := f.disarm(t)
+	t.deadline = f.now.Add(d)
+	if d <= 0 {
+		t.fire(f.now)
+		return armed
+	}
+	f.timers = append(f.timers, t)
+	f.changed.Broadcast()
+	return armed
+}
+
+func (t *fakeTimer) fire(now time.Time) {
+	select {
+	case t.c <- now:
+	default:
+	}
+}
@@ -0,0 +1,148 @@
+package remind
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/boltdb/bolt"
+)
+
+// consentBucket holds a bucket per phone number reminders escalate
+// to, mapping each recipient who asked to escalate to it to its
+// Consent
+var consentBucket = []byte("escalation_consent")
+
+// Consent is whether a phone number agreed to be told when a
+// recipient's reminders aren't done
+type Consent byte
+
+const (
+	ConsentUnasked Consent = iota
+	ConsentAsked           // Waiting for them to reply
+	ConsentGiven
+	ConsentRefused
+)
+
+// GetConsent returns whether contact agreed to be told when owner's
+// reminders aren't done
+func GetConsent(db *bolt.DB, contact, owner string) (Consent, error) {
+	consent := ConsentUnasked
+
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(consentBucket)
+		if b == nil {
+			return nil
+		}
+		theirs := b.Bucket([]byte(contact))
+		if theirs == nil {
+			return nil
+		}
+		if v := theirs.Get([]byte(owner)); len(v) == 1 {
+			consent = Consent(v[0])
+		}
+		return nil
+	})
+
+	return consent, err
+}
+
+// SetConsent records whether contact agreed to be told when owner's
+// reminders aren't done
+func SetConsent(db *bolt.DB, contact, owner string, consent Consent) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(consentBucket)
+		if err != nil {
+			return err
+		}
+		theirs, err := b.CreateBucketIfNotExists([]byte(contact))
+		if err != nil {
+			return err
+		}
+		return theirs.Put([]byte(owner), []byte{byte(consent)})
+	})
+}
+
+// ConsentRequests returns, for each recipient who asked to escalate to
+// contact, whether contact agreed
+func ConsentRequests(db *bolt.DB, contact string) (map[string]Consent, error) {
+	requests := map[string]Consent{}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(consentBucket)
+		if b == nil {
+			return nil
+		}
+		theirs := b.Bucket([]byte(contact))
+		if theirs == nil {
+			return nil
+		}
+		return theirs.ForEach(func(k, v []byte) error {
+			if len(v) == 1 {
+				requests[string(k)] = Consent(v[0])
+			}
+			return nil
+		})
+	})
+
+	return requests, err
+}
+
+// ConsentMessage returns what those r escalates to are texted to ask
+// whether they agree to be told when r's recipient doesn't do things
+func (r *Reminder) ConsentMessage() string {
+	who := r.Recipient
+	if r.RecipientName != "" {
+		who = r.RecipientName + " (" + r.Recipient + ")"
+	}
+	return fmt.Sprintf("%s wants you texted when they don't confirm"+
+		" their reminders. Reply YES to get alerts from %s, or STOP to"+
+		" never get them.", who, r.Recipient)
+}
+
+// AskToEscalate texts each of r's EscalateTo that hasn't been asked
+// before whether they agree to be told when r's recipient's reminders
+// aren't done. Nobody is told until they reply YES; see
+// escalatesTo.
+func (s *Scheduler) AskToEscalate(r *Reminder) error {
+	for _, to := range r.EscalateTo {
+		consent, err := GetConsent(s.db, to, r.Recipient)
+		if err != nil {
+			return err
+		}
+		if consent != ConsentUnasked {
+			continue
+		}
+
+		if err := SetConsent(s.db, to, r.Recipient, ConsentAsked); err != nil {
+			return err
+		}
+		log.Printf("Asking %v whether Reminder %v may escalate to them\n",
+			to, r.ID)
+		_, err = r.sendTo(context.Background(), s.sender, to,
+			r.ConsentMessage())
+		if err != nil {
+			log.Printf("Error asking %v about Reminder %v: %v\n", to, r.ID,
+				err)
+		}
+	}
+	return nil
+}
+
+// escalatesTo returns which of r's EscalateTo agreed to be told when
+// its runs aren't done
+func (r *Reminder) escalatesTo(db *bolt.DB) []string {
+	var agreed []string
+	for _, to := range r.EscalateTo {
+		consent, err := GetConsent(db, to, r.Recipient)
+		if err != nil {
+			log.Printf("Error checking whether %v agreed to hear about"+
+				" Reminder %v: %v\n", to, r.ID, err)
+			continue
+		}
+		if consent == ConsentGiven {
+			agreed = append(agreed, to)
+		}
+	}
+	return agreed
+}
@@ -0,0 +1,309 @@
+package remind
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed 5-field cron expression: minute, hour, day of the
+// month, month, and day of the week.
+type Cron struct {
+	minutes, hours, monthDays, months, weekdays cronField
+
+	// Whether the day-of-month and day-of-week fields start with "*"
+	// (e.g. "*" or "*/2"). If neither does, a day matching either one
+	// matches, like Vixie cron.
+	anyMonthDay, anyWeekday bool
+
+	expr string
+}
+
+// cronField is a bitset of the allowed values of one field
+type cronField uint64
+
+func (f cronField) has(n int) bool {
+	return f&(1<<uint(n)) != 0
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronMonthNames = []string{"", "JAN", "FEB", "MAR", "APR", "MAY", "JUN",
+	"JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+
+var cronWeekdayNames = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+
+// Give up looking for the next time after this many years, e.g. for
+// "0 0 30 2 *"
+const maxCronYears = 5
+
+// ParseCron parses a 5-field cron expression like "0 9 * * 1-5", or a
+// macro like "@daily". Fields may use *, lists (1,15), ranges (1-5),
+// steps (*/15, 0-30/10), and month and weekday names (JAN, MON-FRI).
+func ParseCron(expr string) (*Cron, error) {
+	expr = strings.Join(strings.Fields(expr), " ")
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Cron expression %q must have 5 fields"+
+			" (minute hour day-of-month month day-of-week)", expr)
+	}
+
+	c := &Cron{
+		expr:        expr,
+		anyMonthDay: strings.HasPrefix(fields[2], "*") || fields[2] == "?",
+		anyWeekday:  strings.HasPrefix(fields[4], "*") || fields[4] == "?",
+	}
+
+	var err error
+
+	if c.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("Invalid minute: %v", err)
+	}
+	if c.hours, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("Invalid hour: %v", err)
+	}
+	if c.monthDays, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("Invalid day of the month: %v", err)
+	}
+	if c.months, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return nil, fmt.Errorf("Invalid month: %v", err)
+	}
+	// 7 is also Sunday
+	if c.weekdays, err = parseCronField(fields[4], 0, 7, cronWeekdayNames); err != nil {
+		return nil, fmt.Errorf("Invalid day of the week: %v", err)
+	}
+	if c.weekdays.has(7) {
+		c.weekdays |= 1
+	}
+
+	return c, nil
+}
+
+// parseCronField parses one comma-separated field whose values are
+// between min and max. names, if given, are accepted in place of the
+// numbers they're indexed by.
+func parseCronField(field string, min, max int, names []string) (cronField, error) {
+	var f cronField
+
+	for _, part := range strings.Split(field, ",") {
+		rangeStr, step := part, 1
+
+		if i := strings.Index(part, "/"); i != -1 {
+			var err error
+			rangeStr = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("bad step in %q", part)
+			}
+		}
+
+		lo, hi := min, max
+
+		switch {
+		case rangeStr == "*" || rangeStr == "?":
+			// Full range
+		case strings.Contains(rangeStr, "-"):
+			bounds := strings.SplitN(rangeStr, "-", 2)
+			var err1, err2 error
+			lo, err1 = parseCronValue(bounds[0], names)
+			hi, err2 = parseCronValue(bounds[1], names)
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("bad range %q", rangeStr)
+			}
+		default:
+			n, err := parseCronValue(rangeStr, names)
+			if err != nil {
+				return 0, err
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is out of range (%d-%d)", part, min, max)
+		}
+
+		for n := lo; n <= hi; n += step {
+			f |= 1 << uint(n)
+		}
+	}
+
+	return f, nil
+}
+
+func parseCronValue(s string, names []string) (int, error) {
+	for i, name := range names {
+		if name != "" && strings.EqualFold(s, name) {
+			return i, nil
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("bad value %q", s)
+	}
+	return n, nil
+}
+
+// Next returns the first time after t that c fires, in t's location,
+// or false if it never does. Times daylight saving time skips are
+// moved forward, as WallClock does, so e.g. "30 2 * * *" fires at 3:30
+// the day clocks spring forward rather than not at all.
+func (c *Cron) Next(t time.Time) (time.Time, bool) {
+	year, month, day := t.Date()
+	hour, min, sec := t.Clock()
+	wall := time.Date(year, month, day, hour, min, sec, t.Nanosecond(),
+		time.UTC)
+
+	for {
+		var ok bool
+		if wall, ok = c.nextWall(wall); !ok {
+			return time.Time{}, false
+		}
+
+		// Unless DST moved it to, or repeated it, before t
+		year, month, day := wall.Date()
+		next := WallClock(year, month, day, wall.Hour(), wall.Minute(), 0,
+			t.Location())
+		if next.After(t) {
+			return next, true
+		}
+	}
+}
+
+// nextWall returns the first wall clock time after wall, which is in
+// UTC so DST doesn't apply, that c fires, or false if it never does
+func (c *Cron) nextWall(wall time.Time) (time.Time, bool) {
+	loc := wall.Location()
+	next := wall.Truncate(time.Minute).Add(time.Minute)
+	yearLimit := next.Year() + maxCronYears
+
+	for next.Year() <= yearLimit {
+		year, month, day := next.Date()
+		hour, min := next.Hour(), next.Minute()
+
+		var skipTo time.Time
+
+		switch {
+		case !c.months.has(int(month)):
+			skipTo = time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(next):
+			skipTo = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+		case !c.hours.has(hour):
+			skipTo = time.Date(year, month, day, hour+1, 0, 0, 0, loc)
+		case !c.minutes.has(min):
+			skipTo = time.Date(year, month, day, hour, min+1, 0, 0, loc)
+		default:
+			return next, true
+		}
+
+		next = skipTo
+	}
+
+	return time.Time{}, false
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	monthDay := c.monthDays.has(t.Day())
+	weekday := c.weekdays.has(int(t.Weekday()))
+
+	if c.anyMonthDay || c.anyWeekday {
+		return monthDay && weekday
+	}
+	return monthDay || weekday
+}
+
+// minGap returns the shortest time between two consecutive times c
+// fires (as far as a day apart), not counting daylight saving time
+// changes
+func (c *Cron) minGap() time.Duration {
+	const day = 24 * 60
+
+	// Minutes into the day it fires at
+	var times []int
+	for h := 0; h < 24; h++ {
+		for m := 0; m < 60; m++ {
+			if c.hours.has(h) && c.minutes.has(m) {
+				times = append(times, h*60+m)
+			}
+		}
+	}
+
+	gap := day
+	for i := 1; i < len(times); i++ {
+		if d := times[i] - times[i-1]; d < gap {
+			gap = d
+		}
+	}
+
+	// From the last time one day to the first the next
+	if len(times) > 0 && c.firesOnConsecutiveDays() {
+		if d := times[0] + day - times[len(times)-1]; d < gap {
+			gap = d
+		}
+	}
+
+	return time.Duration(gap) * time.Minute
+}
+
+// firesOnConsecutiveDays reports whether c fires on any two days in a
+// row. Weekdays fall on the same dates every 28 years (between 1901
+// and 2099), so that's as far as it needs to look.
+func (c *Cron) firesOnConsecutiveDays() bool {
+	prev := false
+	for d := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC); d.Year() < 2029; d = d.AddDate(0, 0, 1) {
+		fires := c.months.has(int(d.Month())) && c.dayMatches(d)
+		if fires && prev {
+			return true
+		}
+		prev = fires
+	}
+	return false
+}
+
+func (c *Cron) String() string {
+	if c == nil {
+		return ""
+	}
+	return c.expr
+}
+
+// NewCronReminder returns a Reminder that texts description to
+// recipient whenever the cron expression expr fires in loc (or if nil,
+// LosAngeles), starting now.
+func NewCronReminder(recipient, description, expr string, loc *time.Location) (*Reminder, error) {
+	if recipient == "" || description == "" {
+		return nil, errors.New("Reminder needs a recipient and a description")
+	}
+	if loc == nil {
+		loc = LosAngeles
+	}
+
+	r := &Reminder{
+		Recipient:   recipient,
+		Description: description,
+		NextRun:     Now(),
+		Timezone:    loc.String(),
+		Created:     Now(),
+	}
+	if err := r.SetCron(expr); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
@@ -0,0 +1,225 @@
+package remind
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCron(t *testing.T) {
+	good := []string{
+		"0 9 * * 1-5",
+		"*/15 * * * *",
+		"0,30 8-18/2 1,15 * MON-FRI",
+		"0 0 1 jan *",
+		"0 12 * * 7",
+		"5/10 * * * ?",
+		"@daily",
+		"  0   9  *  *  * ",
+	}
+	for _, expr := range good {
+		_, err := ParseCron(expr)
+		assert.NoError(t, err, "Parsing `%s` should succeed", expr)
+	}
+
+	bad := []string{
+		"",
+		"0 9 * *",
+		"0 9 * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * FOO *",
+		"@reboot",
+	}
+	for _, expr := range bad {
+		_, err := ParseCron(expr)
+		assert.Error(t, err, "Parsing `%s` should fail", expr)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, LosAngeles)
+	}
+
+	// Sunday
+	start := at(10, 18, 10, 7)
+
+	tests := []struct {
+		expr string
+		from time.Time
+		next []time.Time
+	}{
+		{
+			"0 9 * * 1-5",
+			start,
+			[]time.Time{at(10, 19, 9, 0), at(10, 20, 9, 0)},
+		},
+		{
+			"*/15 * * * *",
+			start,
+			[]time.Time{at(10, 18, 10, 15), at(10, 18, 10, 30)},
+		},
+		{
+			// Day of month OR day of week
+			"30 8 13 * FRI",
+			start,
+			[]time.Time{at(10, 23, 8, 30), at(10, 30, 8, 30),
+				at(11, 6, 8, 30), at(11, 13, 8, 30)},
+		},
+		{
+			// Odd days that are Mondays, as "*/2" counts as "*" for
+			// the OR above
+			"0 9 */2 * MON",
+			start,
+			[]time.Time{at(10, 19, 9, 0), at(11, 9, 9, 0),
+				at(11, 23, 9, 0)},
+		},
+		{
+			"0 0 1 1 *",
+			start,
+			[]time.Time{time.Date(2027, 1, 1, 0, 0, 0, 0, LosAngeles)},
+		},
+		{
+			"@monthly",
+			start,
+			[]time.Time{at(11, 1, 0, 0), at(12, 1, 0, 0)},
+		},
+		{
+			// Same wall-clock time across the end of DST (Nov 1)
+			"0 9 * * *",
+			at(10, 31, 9, 0),
+			[]time.Time{at(11, 1, 9, 0), at(11, 2, 9, 0)},
+		},
+		{
+			// 2:30am doesn't exist on Mar 8, 2026, so it's 3:30am
+			"30 2 * * *",
+			time.Date(2026, 3, 7, 3, 0, 0, 0, LosAngeles),
+			[]time.Time{time.Date(2026, 3, 8, 3, 30, 0, 0, LosAngeles),
+				time.Date(2026, 3, 9, 2, 30, 0, 0, LosAngeles)},
+		},
+		{
+			// Which is sent once
+			"30 2,3 * * *",
+			time.Date(2026, 3, 7, 4, 0, 0, 0, LosAngeles),
+			[]time.Time{time.Date(2026, 3, 8, 3, 30, 0, 0, LosAngeles),
+				time.Date(2026, 3, 9, 2, 30, 0, 0, LosAngeles)},
+		},
+		{
+			// 1:30am happens twice on Nov 1, but is sent once
+			"30 1 * * *",
+			at(10, 31, 9, 0),
+			[]time.Time{time.Date(2026, 11, 1, 1, 30, 0, 0, LosAngeles),
+				at(11, 2, 1, 30)},
+		},
+	}
+
+	for _, test := range tests {
+		c, err := ParseCron(test.expr)
+		if err != nil {
+			t.Errorf("Error parsing `%s`: %v", test.expr, err)
+			continue
+		}
+
+		prev := test.from
+		for _, want := range test.next {
+			got, ok := c.Next(prev)
+			if !assert.True(t, ok) {
+				break
+			}
+			assert.Equal(t, want, got, "Wrong time for `%s` after %s",
+				test.expr, prev)
+			prev = got
+		}
+	}
+
+	// Not again during the repeated hour
+	c, _ := ParseCron("30 1 * * *")
+	pdt := time.Date(2026, 11, 1, 1, 30, 0, 0, LosAngeles)
+	next, _ := c.Next(pdt.Add(40 * time.Minute)) // 1:10am PST
+	assert.Equal(t, at(11, 2, 1, 30), next)
+	next, _ = c.Next(pdt.Add(-time.Minute))
+	assert.Equal(t, pdt, next)
+
+	// Never fires
+	c, _ = ParseCron("0 0 30 2 *")
+	_, ok := c.Next(start)
+	assert.False(t, ok)
+}
+
+func TestCronMinGap(t *testing.T) {
+	tests := []struct {
+		expr string
+		gap  time.Duration
+	}{
+		{"* * * * *", time.Minute},
+		{"*/15 * * * *", 15 * time.Minute},
+		{"0 9 * * 1-5", 24 * time.Hour},
+		{"0 9,17 * * *", 8 * time.Hour},
+		// 11:55pm, then 12am the next day
+		{"0,55 0,23 * * *", 5 * time.Minute},
+		// But never the next day
+		{"0,55 0,23 * * MON", 55 * time.Minute},
+		{"0,55 0,23 1 * *", 55 * time.Minute},
+		{"0,55 0,23 28,1 * *", 5 * time.Minute},
+	}
+	for _, test := range tests {
+		c, err := ParseCron(test.expr)
+		if assert.NoError(t, err, test.expr) {
+			assert.Equal(t, test.gap, c.minGap(), test.expr)
+		}
+	}
+
+	// Reminders can't fire more often than every MinPeriod
+	for _, expr := range []string{"* * * * *", "*/10 9 * * *",
+		"0,55 0,23 * * *"} {
+		_, err := NewCronReminder("+15555555555", "Breathe", expr, nil)
+		assert.Error(t, err, expr)
+	}
+	_, err := NewCronReminder("+15555555555", "Breathe", "*/15 9 * * *", nil)
+	assert.NoError(t, err)
+
+	// Even if saved that way
+	r := &Reminder{NextRun: Now().Add(time.Hour), Cron: "* * * * *"}
+	assert.Error(t, r.Check(nil))
+}
+
+func TestNewCronReminder(t *testing.T) {
+	_, err := NewCronReminder("+15555555555", "Stand up", "0 9 * *", nil)
+	assert.Error(t, err)
+
+	r, err := NewCronReminder("+15555555555", "Stand up", "0 9 * * 1-5", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.True(t, r.Repeats())
+	assert.Equal(t, "cron 0 9 * * 1-5", r.Repetition())
+	assert.True(t, r.NextRun.After(Now()))
+	assert.Equal(t, "09:00", r.NextRun.In(LosAngeles).Format("15:04"))
+	assert.Contains(t, Weekdays, r.NextRun.Weekday())
+
+	next, ok := r.next(r.NextRun)
+	assert.True(t, ok)
+	assert.True(t, next.After(r.NextRun))
+	assert.Contains(t, Weekdays, next.Weekday())
+
+	// In the given timezone
+	berlin, err := LoadLocation("Europe/Berlin")
+	if !assert.NoError(t, err) {
+		return
+	}
+	r, err = NewCronReminder("+15555555555", "Stand up", "0 9 * * 1-5", berlin)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Europe/Berlin", r.Timezone)
+		assert.Equal(t, "09:00", r.NextRun.In(berlin).Format("15:04"))
+		next, _ := r.next(r.NextRun)
+		assert.Equal(t, "09:00", next.In(berlin).Format("15:04"))
+	}
+}
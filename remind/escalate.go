@@ -0,0 +1,96 @@
+package remind
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// DefaultEscalateAfter is how long a run may go unacknowledged
+	// before the reminder's EscalateTo are told, unless it says
+	DefaultEscalateAfter = 30 * time.Minute
+
+	// MaxEscalateTo is how many others a reminder may tell
+	MaxEscalateTo = 3
+)
+
+// escalates reports whether anyone is told when r's runs aren't
+// acknowledged
+func (r *Reminder) escalates() bool {
+	return len(r.EscalateTo) > 0
+}
+
+// escalateAfter returns how long r's runs may go unacknowledged
+// before its EscalateTo are told
+func (r *Reminder) escalateAfter() time.Duration {
+	if r.EscalateAfter > 0 {
+		return r.EscalateAfter
+	}
+	return DefaultEscalateAfter
+}
+
+// EscalationMessage returns what r's EscalateTo are texted when a run
+// isn't acknowledged, e.g. "Mom hasn't confirmed: Take pills"
+func (r *Reminder) EscalationMessage() string {
+	name := r.RecipientName
+	if name == "" {
+		name = r.Recipient
+	}
+	return fmt.Sprintf("%s hasn't confirmed: %s", name, r.Description)
+}
+
+// escalate texts those of e's reminder's EscalateTo who agreed to it
+// that the run e.occ wasn't acknowledged in time, recording who was in
+// its history, then schedules whatever's sent next
+func (s *Scheduler) escalate(e *scheduled) {
+	r := e.r
+
+	// As with send, nothing else changes what this reads
+	var told []string
+	for _, to := range r.escalatesTo(s.db) {
+		log.Printf("Telling %v that Reminder %v wasn't done\n", to, r.ID)
+		msgID, err := r.sendTo(context.Background(), s.sender, to,
+			r.EscalationMessage())
+		if err != nil {
+			log.Printf("Error escalating Reminder %v to %v: %v\n", r.ID, to,
+				err)
+			continue
+		}
+		log.Printf("Reminder %v escalated to %v as message %v\n", r.ID, to,
+			msgID)
+		told = append(told, to)
+	}
+
+	if err := s.save(s.escalated(e, told)); err != nil {
+		log.Printf("Error saving Reminder %v: %v\n", r.ID, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e.cancelled {
+		return
+	}
+	s.requeue(e)
+}
+
+// escalated records that e's reminder's run e.occ was escalated to
+// told, returning the reminder as of then to be saved
+func (s *Scheduler) escalated(e *scheduled, told []string) *snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Even if acknowledged or cancelled meanwhile, as it was sent
+	occ := e.occ
+	occ.EscalateAt = time.Time{}
+	if len(told) > 0 {
+		occ.Escalated = Now()
+		occ.EscalatedTo = told
+	}
+	if e.r.Pending == occ {
+		e.r.settle()
+	}
+	return s.snapshot(e)
+}
@@ -0,0 +1,164 @@
+package remind
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const caregiver = "+15555550111"
+
+// agree records that each of contacts agreed to be told about
+// addTestReminder's reminders
+func agree(t *testing.T, s *Scheduler, contacts ...string) {
+	for _, to := range contacts {
+		if err := SetConsent(s.db, to, "+15555550100", ConsentGiven); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSchedulerEscalates(t *testing.T) {
+	s, clk, sent := newTestScheduler(t)
+	start := time.Date(2026, 6, 1, 9, 0, 0, 0, LosAngeles)
+	r := &Reminder{NextRun: start, Period: 24 * time.Hour,
+		NagEvery: 10 * time.Minute, MaxNags: 1,
+		EscalateTo: []string{caregiver}, EscalateAfter: 30 * time.Minute,
+		RecipientName: "Mom"}
+	addTestReminder(t, s, r)
+	agree(t, s, caregiver)
+
+	clk.Advance(time.Hour)
+	assert.Equal(t, 1, s.sendDue())
+
+	// Sent again once...
+	clk.Advance(10 * time.Minute)
+	assert.Equal(t, 1, s.sendDue())
+	if assert.NotNil(t, r.Pending, "Waiting to escalate") {
+		assert.True(t, r.Pending.NextNag.IsZero())
+	}
+
+	// ...then escalated
+	_, wait := s.popDue()
+	assert.Equal(t, 20*time.Minute, wait)
+	clk.Advance(wait)
+	assert.Equal(t, 1, s.sendDue())
+	msgs := sent.Sent()
+	if assert.Len(t, msgs, 3) {
+		assert.Equal(t, caregiver, msgs[2].To)
+		assert.Equal(t, "Mom hasn't confirmed: Test", msgs[2].Body)
+	}
+
+	assert.Nil(t, r.Pending)
+	if assert.Len(t, r.History, 1) {
+		assert.Equal(t, Now(), r.History[0].Escalated)
+		assert.Equal(t, []string{caregiver}, r.History[0].EscalatedTo)
+	}
+	_, wait = s.popDue()
+	assert.Equal(t, 24*time.Hour-30*time.Minute, wait)
+
+	// Done in time, so nobody's told
+	clk.Advance(wait)
+	assert.Equal(t, 1, s.sendDue())
+	clk.Advance(20 * time.Minute)
+	_, err := s.Ack(r.Recipient, r.ID)
+	assert.NoError(t, err)
+	clk.Advance(20 * time.Minute)
+	assert.Equal(t, 0, s.sendDue())
+	assert.Len(t, sent.Sent(), 4)
+
+	saved, err := GetReminder(s.db, r.ID)
+	if assert.NoError(t, err) && assert.Len(t, saved.History, 2) {
+		assert.False(t, saved.History[0].Escalated.IsZero())
+		assert.True(t, saved.History[1].Escalated.IsZero())
+		assert.False(t, saved.History[1].Acked.IsZero())
+	}
+}
+
+func TestEscalateOneShot(t *testing.T) {
+	s, clk, sent := newTestScheduler(t)
+	r := &Reminder{NextRun: Now().Add(time.Hour),
+		EscalateTo: []string{caregiver, "+15555550112"}}
+	addTestReminder(t, s, r)
+	agree(t, s, caregiver, "+15555550112")
+
+	clk.Advance(time.Hour)
+	assert.Equal(t, 1, s.sendDue())
+	assert.Equal(t, `Reminder 1: Test (reply "done 1" when done)`,
+		sent.Last().Body)
+	assert.True(t, r.Completed)
+	assert.Equal(t, 1, s.Len())
+
+	clk.Advance(DefaultEscalateAfter)
+	assert.Equal(t, 1, s.sendDue())
+	msgs := sent.Sent()
+	if assert.Len(t, msgs, 3) {
+		assert.Equal(t, "+15555550100 hasn't confirmed: Test", msgs[1].Body)
+		assert.Equal(t, "+15555550112", msgs[2].To)
+	}
+	assert.Equal(t, 0, s.Len())
+
+	// Done late still counts
+	occ, err := s.Ack(r.Recipient, r.ID)
+	if assert.NoError(t, err) {
+		assert.Len(t, occ.EscalatedTo, 2)
+		assert.Equal(t, Now(), occ.Acked)
+	}
+}
+
+func TestAskToEscalate(t *testing.T) {
+	s, clk, sent := newTestScheduler(t)
+	r := &Reminder{NextRun: Now().Add(time.Hour), Period: 24 * time.Hour,
+		EscalateTo: []string{caregiver, "+15555550112"}, RecipientName: "Mom"}
+	addTestReminder(t, s, r)
+
+	// Each is asked once
+	assert.NoError(t, s.AskToEscalate(r))
+	assert.NoError(t, s.AskToEscalate(r))
+	msgs := sent.Sent()
+	if assert.Len(t, msgs, 2) {
+		assert.Equal(t, caregiver, msgs[0].To)
+		assert.Equal(t, "+15555550112", msgs[1].To)
+		assert.Equal(t, "Mom (+15555550100) wants you texted when they don't"+
+			" confirm their reminders. Reply YES to get alerts from"+
+			" +15555550100, or STOP to never get them.", msgs[0].Body)
+	}
+	consent, err := GetConsent(s.db, caregiver, r.Recipient)
+	assert.NoError(t, err)
+	assert.Equal(t, ConsentAsked, consent)
+
+	// Only those who agreed are told
+	assert.NoError(t, SetConsent(s.db, caregiver, r.Recipient, ConsentGiven))
+	clk.Advance(time.Hour)
+	assert.Equal(t, 1, s.sendDue())
+	clk.Advance(DefaultEscalateAfter)
+	assert.Equal(t, 1, s.sendDue())
+	msgs = sent.Sent()
+	if assert.Len(t, msgs, 4) {
+		assert.Equal(t, caregiver, msgs[3].To)
+	}
+	if assert.Len(t, r.History, 1) {
+		assert.Equal(t, []string{caregiver}, r.History[0].EscalatedTo)
+	}
+
+	// Nobody is once they've opted out
+	assert.NoError(t, SetConsent(s.db, caregiver, r.Recipient, ConsentRefused))
+	clk.Advance(24*time.Hour - DefaultEscalateAfter)
+	assert.Equal(t, 1, s.sendDue())
+	clk.Advance(DefaultEscalateAfter)
+	assert.Equal(t, 1, s.sendDue())
+	assert.Len(t, sent.Sent(), 5)
+	if assert.Len(t, r.History, 2) {
+		assert.True(t, r.History[1].Escalated.IsZero())
+		assert.Empty(t, r.History[1].EscalatedTo)
+	}
+
+	// Nor asked again
+	assert.NoError(t, s.AskToEscalate(r))
+	assert.Len(t, sent.Sent(), 5)
+
+	requests, err := ConsentRequests(s.db, caregiver)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Consent{r.Recipient: ConsentRefused}, requests)
+}
@@ -0,0 +1,110 @@
+package remind
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MissedPolicy says what to do about the runs a reminder missed, e.g.
+// while the server was down
+type MissedPolicy string
+
+const (
+	MissedSkip MissedPolicy = "skip" // Don't send them
+	MissedLate MissedPolicy = "late" // Send the latest one, marked late
+	MissedAll  MissedPolicy = "all"  // Send each, up to MaxMissed of them
+)
+
+var (
+	// DefaultMissed is the policy of reminders without their own
+	DefaultMissed = MissedSkip
+
+	// MaxMissed is how many of a reminder's most recent missed runs
+	// MissedAll sends
+	MaxMissed = 5
+)
+
+// ParseMissedPolicy parses "skip", "late", "all", or "all N", where N
+// is a MaxMissed to use (0 if not given)
+func ParseMissedPolicy(s string) (policy MissedPolicy, max int, err error) {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) == 0 {
+		return "", 0, fmt.Errorf("Missed-run policy %q is empty", s)
+	}
+
+	policy = MissedPolicy(fields[0])
+	switch {
+	case policy != MissedSkip && policy != MissedLate && policy != MissedAll:
+		return "", 0, fmt.Errorf("Missed-run policy must be skip, late, or"+
+			" all, not %q", fields[0])
+	case len(fields) == 1:
+		return policy, 0, nil
+	case len(fields) > 2 || policy != MissedAll:
+		return "", 0, fmt.Errorf("Invalid missed-run policy %q", s)
+	}
+
+	max, err = strconv.Atoi(fields[1])
+	if err != nil || max < 1 {
+		return "", 0, fmt.Errorf("Invalid number of missed runs to send: %q",
+			fields[1])
+	}
+	return policy, max, nil
+}
+
+// missedPolicy returns r's Missed policy, or DefaultMissed
+func (r *Reminder) missedPolicy() MissedPolicy {
+	if r.Missed == "" {
+		return DefaultMissed
+	}
+	return r.Missed
+}
+
+// catchUp schedules r's missed runs to be sent late, if its policy
+// says to, by setting Nominal to the first of them and Late to how
+// many. Later ones then follow immediately, since next(Nominal) is
+// still in the past.
+func (r *Reminder) catchUp() (changed bool) {
+	if r.Late > 0 {
+		// Already catching up
+		return false
+	}
+
+	max := 1
+	switch r.missedPolicy() {
+	case MissedSkip:
+		return false
+	case MissedAll:
+		max = MaxMissed
+	}
+	if r.MaxRuns != 0 && r.MaxRuns-r.Runs < max {
+		max = r.MaxRuns - r.Runs
+	}
+
+	now := Now()
+	var missed []time.Time
+	total := 0
+	for t, ok := r.Nominal, true; ok && !t.After(now); t, ok = r.next(t) {
+		if !r.Until.IsZero() && t.After(r.Until) {
+			break
+		}
+		total++
+		missed = append(missed, t)
+		if len(missed) > max {
+			missed = missed[1:]
+		}
+	}
+	if len(missed) == 0 {
+		return false
+	}
+
+	log.Printf("Reminder %v missed %d run(s); sending %d late\n", r.ID,
+		total, len(missed))
+
+	r.setNext(missed[0])
+	r.Late = len(missed)
+
+	return true
+}
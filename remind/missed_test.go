@@ -0,0 +1,138 @@
+package remind
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMissedPolicy(t *testing.T) {
+	tests := []struct {
+		s      string
+		policy MissedPolicy
+		max    int
+	}{
+		{"skip", MissedSkip, 0},
+		{"Late", MissedLate, 0},
+		{"all", MissedAll, 0},
+		{" all 3 ", MissedAll, 3},
+	}
+	for _, test := range tests {
+		policy, max, err := ParseMissedPolicy(test.s)
+		if assert.NoError(t, err, test.s) {
+			assert.Equal(t, test.policy, policy, test.s)
+			assert.Equal(t, test.max, max, test.s)
+		}
+	}
+
+	for _, s := range []string{"", "resend", "late 3", "all 0", "all three",
+		"all 3 4"} {
+		_, _, err := ParseMissedPolicy(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestMissedOneShot(t *testing.T) {
+	s, _, sent := newTestScheduler(t)
+
+	skipped := &Reminder{NextRun: Now().Add(-2 * time.Hour)}
+	addTestReminder(t, s, skipped)
+	assert.True(t, skipped.Cancelled)
+	assert.Equal(t, 0, s.Len())
+
+	late := &Reminder{NextRun: Now().Add(-2 * time.Hour), Missed: MissedLate}
+	addTestReminder(t, s, late)
+	assert.Equal(t, 1, late.Late)
+	assert.Equal(t, 1, s.sendDue())
+	if assert.Len(t, sent.Sent(), 1) {
+		assert.True(t, strings.HasSuffix(sent.Last().Body, "Test (late)"))
+	}
+	assert.True(t, late.Completed)
+	assert.Equal(t, 0, late.Late)
+}
+
+func TestMissedRecurring(t *testing.T) {
+	now := time.Date(2026, 6, 1, 8, 0, 0, 0, LosAngeles)
+	start := now.Add(-10*time.Hour - 30*time.Minute)
+
+	// Hourly, last sent 5.5 hours ago, so 5 runs were missed
+	hourly := func(missed MissedPolicy, maxRuns int) *Reminder {
+		return &Reminder{
+			NextRun: now.Add(-4*time.Hour - 30*time.Minute),
+			Start:   start,
+			Period:  time.Hour,
+			Runs:    6,
+			MaxRuns: maxRuns,
+			Missed:  missed,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		r        *Reminder
+		late     int
+		sendLate []time.Time
+	}{
+		{"Skip", hourly(MissedSkip, 0), 0, nil},
+		{"Late", hourly(MissedLate, 0), 1,
+			[]time.Time{now.Add(-30 * time.Minute)}},
+		{"All", hourly(MissedAll, 0), 3,
+			[]time.Time{now.Add(-150 * time.Minute),
+				now.Add(-90 * time.Minute), now.Add(-30 * time.Minute)}},
+		{"All, but only 2 runs left", hourly(MissedAll, 8), 2,
+			[]time.Time{now.Add(-90 * time.Minute), now.Add(-30 * time.Minute)}},
+	}
+
+	prevMax := MaxMissed
+	MaxMissed = 3
+	defer func() { MaxMissed = prevMax }()
+
+	for _, test := range tests {
+		s, clk, sent := newTestScheduler(t)
+		r := test.r
+		addTestReminder(t, s, r)
+		assert.Equal(t, test.late, r.Late, test.name)
+
+		for _, nominal := range test.sendLate {
+			assert.Equal(t, nominal, r.Nominal, test.name)
+			assert.Equal(t, 1, s.sendDue(), test.name)
+			assert.True(t, strings.HasSuffix(sent.Last().Body, "(late)"),
+				test.name)
+		}
+		assert.Equal(t, 0, r.Late, test.name)
+		if r.Completed {
+			assert.Equal(t, r.MaxRuns, r.Runs, test.name)
+			continue
+		}
+
+		// Then back on schedule
+		assert.Equal(t, now.Add(30*time.Minute), r.Nominal, test.name)
+		clk.Advance(30 * time.Minute)
+		assert.Equal(t, 1, s.sendDue(), test.name)
+		assert.Equal(t, "Test", strings.SplitN(sent.Last().Body, ": ", 2)[1],
+			test.name)
+	}
+}
+
+func TestMissedDefault(t *testing.T) {
+	prev := DefaultMissed
+	DefaultMissed = MissedLate
+	defer func() { DefaultMissed = prev }()
+
+	s, _, _ := newTestScheduler(t)
+	r := &Reminder{NextRun: Now().Add(-time.Minute)}
+	addTestReminder(t, s, r)
+	assert.False(t, r.Cancelled)
+	assert.Equal(t, 1, r.Late)
+
+	// Saved, so a restart before it's sent doesn't lose track
+	saved, err := GetAllReminders(s.db)
+	if assert.NoError(t, err) && assert.Len(t, saved, 1) {
+		assert.Equal(t, 1, saved[0].Late)
+		assert.NoError(t, saved[0].Check(s.db))
+		assert.Equal(t, 1, saved[0].Late)
+		assert.False(t, saved[0].Cancelled)
+	}
+}
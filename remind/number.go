@@ -0,0 +1,103 @@
+package remind
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"log"
+
+	"github.com/boltdb/bolt"
+)
+
+// numberBucket holds a bucket per recipient, mapping each of their
+// reminders' Numbers to its ID
+var numberBucket = []byte("reminder_number")
+
+// assignNumber gives r the next Number among its recipient's
+// reminders, if it doesn't have one
+func (r *Reminder) assignNumber(tx *bolt.Tx) error {
+	if r.Number != 0 || r.Recipient == "" {
+		return nil
+	}
+
+	numbers, err := tx.CreateBucketIfNotExists(numberBucket)
+	if err != nil {
+		return err
+	}
+	theirs, err := numbers.CreateBucketIfNotExists([]byte(r.Recipient))
+	if err != nil {
+		return err
+	}
+
+	r.Number, _ = theirs.NextSequence()
+	return theirs.Put(itob(r.Number), r.IDBytes())
+}
+
+// ReminderID returns the ID of recipient's reminder with the given
+// Number
+func ReminderID(db *bolt.DB, recipient string, number uint64) (uint64, error) {
+	var id uint64
+
+	err := db.View(func(tx *bolt.Tx) error {
+		numbers := tx.Bucket(numberBucket)
+		if numbers == nil {
+			return ErrReminderNotFound
+		}
+		theirs := numbers.Bucket([]byte(recipient))
+		if theirs == nil {
+			return ErrReminderNotFound
+		}
+		v := theirs.Get(itob(number))
+		if v == nil {
+			return ErrReminderNotFound
+		}
+		id = binary.BigEndian.Uint64(v)
+		return nil
+	})
+
+	return id, err
+}
+
+// NumberReminders gives each reminder saved before Numbers existed
+// one, in the order they were created
+func NumberReminders(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(boltBucket)
+		if err != nil {
+			return err
+		}
+
+		var unnumbered Reminders
+		err = b.ForEach(func(k, v []byte) error {
+			var rem Reminder
+			if err := json.Unmarshal(v, &rem); err != nil {
+				return err
+			}
+			if rem.Number == 0 {
+				rem.ID = binary.BigEndian.Uint64(k)
+				unnumbered = append(unnumbered, &rem)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, rem := range unnumbered {
+			if err := rem.assignNumber(tx); err != nil {
+				return err
+			}
+			remBytes, err := json.Marshal(rem)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(rem.IDBytes(), remBytes); err != nil {
+				return err
+			}
+		}
+
+		if len(unnumbered) > 0 {
+			log.Printf("Numbered %d reminders\n", len(unnumbered))
+		}
+		return nil
+	})
+}
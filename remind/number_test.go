@@ -0,0 +1,80 @@
+package remind
+
+import (
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReminderNumbers(t *testing.T) {
+	db := openTestDB(t)
+	alice, bob := "+15555550100", "+15555550101"
+
+	// Saved before Numbers existed
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(boltBucket)
+		if err != nil {
+			return err
+		}
+		for _, recipient := range []string{bob, alice} {
+			id, _ := b.NextSequence()
+			legacy := `{"Recipient":"` + recipient + `","Description":"Old"}`
+			if err := b.Put(itob(id), []byte(legacy)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, NumberReminders(db))
+	assert.NoError(t, NumberReminders(db), "Nothing left to number")
+
+	var rems Reminders
+	for _, recipient := range []string{alice, bob, alice} {
+		r := &Reminder{Recipient: recipient, Description: "New",
+			NextRun: Now().Add(time.Hour)}
+		if !assert.NoError(t, r.Save(db)) {
+			return
+		}
+		rems = append(rems, r)
+	}
+
+	saved, err := GetAllReminders(db)
+	if !assert.NoError(t, err) {
+		return
+	}
+	nums := map[string][]uint64{}
+	for _, r := range saved {
+		nums[r.Recipient] = append(nums[r.Recipient], r.Number)
+
+		id, err := ReminderID(db, r.Recipient, r.Number)
+		assert.NoError(t, err)
+		assert.Equal(t, r.ID, id)
+	}
+	assert.Equal(t, []uint64{1, 2, 3}, nums[alice])
+	assert.Equal(t, []uint64{1, 2}, nums[bob])
+
+	assert.Equal(t, "Reminder 3: New", rems[2].Message())
+
+	// Looked up by Number
+	theirs, err := GetRemindersFor(db, alice)
+	if assert.NoError(t, err) && assert.Len(t, theirs, 3) {
+		for i, r := range theirs {
+			assert.Equal(t, uint64(i+1), r.Number)
+			assert.Equal(t, alice, r.Recipient)
+		}
+		assert.Equal(t, rems[2].ID, theirs[2].ID)
+	}
+	theirs, err = GetRemindersFor(db, "+15555550199")
+	assert.NoError(t, err)
+	assert.Len(t, theirs, 0)
+
+	_, err = ReminderID(db, alice, 4)
+	assert.Equal(t, ErrReminderNotFound, err)
+	_, err = ReminderID(db, "+15555550199", 1)
+	assert.Equal(t, ErrReminderNotFound, err)
+}
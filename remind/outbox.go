@@ -0,0 +1,316 @@
+package remind
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/elimisteve/do_reminder/twilhelp"
+)
+
+var (
+	outboxBucket = []byte("outbox")
+	failedBucket = []byte("outbox_failed")
+)
+
+const (
+	// DefaultMaxAttempts is how many times an Outbox tries to send a
+	// message before giving up on it, unless told otherwise
+	DefaultMaxAttempts = 8
+
+	// DefaultBackoff is how long an Outbox waits to retry a message
+	// the first time sending it fails; each retry after waits twice as
+	// long, up to DefaultMaxBackoff
+	DefaultBackoff    = 30 * time.Second
+	DefaultMaxBackoff = time.Hour
+)
+
+// OutMessage is a text waiting to be sent by an Outbox, or one it gave
+// up on
+type OutMessage struct {
+	ID         uint64
+	ReminderID uint64 `json:",omitempty"` // 0 for replies
+	To         string
+	Body       string
+	Created    time.Time
+
+	Attempts    int `json:",omitempty"`
+	NextAttempt time.Time
+	LastError   string `json:",omitempty"`
+}
+
+// Outbox is a Sender that saves each message to bolt, then sends it,
+// retrying with exponential backoff until it's sent or MaxAttempts
+// have failed, at which point it's recorded (see FailedMessages).
+// Messages still waiting when the server stops are sent once it
+// restarts.
+type Outbox struct {
+	db     *bolt.DB
+	sender twilhelp.Sender
+
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+
+	wake chan struct{}
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewOutbox returns an Outbox that queues messages in db and sends
+// them with sender
+func NewOutbox(db *bolt.DB, sender twilhelp.Sender) *Outbox {
+	return &Outbox{
+		db:          db,
+		sender:      sender,
+		MaxAttempts: DefaultMaxAttempts,
+		Backoff:     DefaultBackoff,
+		MaxBackoff:  DefaultMaxBackoff,
+		wake:        make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+	}
+}
+
+// Start starts sending queued messages
+func (o *Outbox) Start() {
+	o.wg.Add(1)
+	go o.run()
+}
+
+// Stop stops sending messages, after waiting for the one being sent
+func (o *Outbox) Stop() {
+	close(o.stop)
+	o.wg.Wait()
+}
+
+// Send queues body to be texted to to, on behalf of the reminder ctx
+// is about, if any. The returned MessageID is the Outbox's, not the
+// underlying Sender's.
+func (o *Outbox) Send(ctx context.Context, to, body string) (twilhelp.MessageID, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return o.enqueue(reminderIDFrom(ctx), to, body)
+}
+
+// reminderIDKey is the context key for the ID of the reminder a
+// message is about
+type reminderIDKey struct{}
+
+// withReminderID returns a copy of ctx saying that messages sent with
+// it are about the reminder with ID id
+func withReminderID(ctx context.Context, id uint64) context.Context {
+	return context.WithValue(ctx, reminderIDKey{}, id)
+}
+
+// reminderIDFrom returns the ID of the reminder messages sent with ctx
+// are about, or 0 for none
+func reminderIDFrom(ctx context.Context) uint64 {
+	id, _ := ctx.Value(reminderIDKey{}).(uint64)
+	return id
+}
+
+// enqueue queues body to be texted to to on behalf of the reminder
+// with ID reminderID (or 0 for none)
+func (o *Outbox) enqueue(reminderID uint64, to, body string) (twilhelp.MessageID, error) {
+	now := Now()
+	msg := &OutMessage{
+		ReminderID:  reminderID,
+		To:          to,
+		Body:        body,
+		Created:     now,
+		NextAttempt: now,
+	}
+
+	err := o.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(outboxBucket)
+		if err != nil {
+			return err
+		}
+
+		msg.ID, _ = b.NextSequence()
+
+		msgBytes, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		return b.Put(itob(msg.ID), msgBytes)
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error queueing message to %v: %v", to, err)
+	}
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+
+	return twilhelp.MessageID(fmt.Sprintf("outbox-%d", msg.ID)), nil
+}
+
+// Pending returns the messages waiting to be sent, oldest first
+func (o *Outbox) Pending() ([]*OutMessage, error) {
+	return outMessages(o.db, outboxBucket, func(*OutMessage) bool { return true })
+}
+
+// FailedMessages returns the messages for the reminder with ID
+// reminderID that an Outbox gave up sending, oldest first
+func FailedMessages(db *bolt.DB, reminderID uint64) ([]*OutMessage, error) {
+	return outMessages(db, failedBucket, func(msg *OutMessage) bool {
+		return msg.ReminderID == reminderID
+	})
+}
+
+// outMessages returns the messages in bucket that keep returns true
+// for, by ID
+func outMessages(db *bolt.DB, bucket []byte, keep func(*OutMessage) bool) ([]*OutMessage, error) {
+	var msgs []*OutMessage
+
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var msg OutMessage
+			if err := json.Unmarshal(v, &msg); err != nil {
+				return err
+			}
+			if keep(&msg) {
+				msgs = append(msgs, &msg)
+			}
+			return nil
+		})
+	})
+
+	return msgs, err
+}
+
+func (o *Outbox) run() {
+	defer o.wg.Done()
+
+	for {
+		timer := clock.NewTimer(o.sendDue())
+
+		select {
+		case <-timer.C():
+		case <-o.wake:
+		case <-o.stop:
+			timer.Stop()
+			return
+		}
+		timer.Stop()
+	}
+}
+
+// sendDue tries to send each message due to be, returning how long
+// until the next one is
+func (o *Outbox) sendDue() (wait time.Duration) {
+	msgs, err := o.Pending()
+	if err != nil {
+		log.Printf("Error getting queued messages: %v\n", err)
+		return o.Backoff
+	}
+
+	sort.Slice(msgs, func(i, j int) bool {
+		return msgs[i].NextAttempt.Before(msgs[j].NextAttempt)
+	})
+
+	// Until something's queued
+	wait = time.Hour
+
+	for _, msg := range msgs {
+		if !msg.NextAttempt.After(Now()) {
+			select {
+			case <-o.stop:
+				return wait
+			default:
+			}
+
+			if !o.send(msg) {
+				continue
+			}
+		}
+
+		if until := msg.NextAttempt.Sub(Now()); until < wait {
+			wait = until
+		}
+	}
+
+	return wait
+}
+
+// send tries to send msg, then removes it from the queue, or if it
+// failed, either schedules its next attempt (returning true) or
+// records that it failed for good
+func (o *Outbox) send(msg *OutMessage) (retry bool) {
+	msg.Attempts++
+
+	id, err := o.sender.Send(context.Background(), msg.To, msg.Body)
+	if err == nil {
+		log.Printf("Sent queued message %v to %v as %v\n", msg.ID, msg.To, id)
+		o.save(msg, nil)
+		return false
+	}
+
+	msg.LastError = err.Error()
+
+	if msg.Attempts >= o.MaxAttempts {
+		log.Printf("Giving up on queued message %v to %v after %d attempts:"+
+			" %v\n", msg.ID, msg.To, msg.Attempts, err)
+		o.save(msg, failedBucket)
+		return false
+	}
+
+	msg.NextAttempt = Now().Add(o.backoff(msg.Attempts))
+	log.Printf("Error sending queued message %v to %v (attempt %d of %d);"+
+		" retrying at %s: %v\n", msg.ID, msg.To, msg.Attempts,
+		o.MaxAttempts, msg.NextAttempt, err)
+	o.save(msg, outboxBucket)
+	return true
+}
+
+// backoff returns how long to wait after the given number of failed
+// attempts before trying again
+func (o *Outbox) backoff(attempts int) time.Duration {
+	wait := o.Backoff
+	for i := 1; i < attempts && wait < o.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > o.MaxBackoff {
+		wait = o.MaxBackoff
+	}
+	return wait
+}
+
+// save removes msg from the queue and, unless bucket is nil, puts it
+// in bucket
+func (o *Outbox) save(msg *OutMessage, bucket []byte) {
+	err := o.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(outboxBucket).Delete(itob(msg.ID)); err != nil {
+			return err
+		}
+		if bucket == nil {
+			return nil
+		}
+
+		b, err := tx.CreateBucketIfNotExists(bucket)
+		if err != nil {
+			return err
+		}
+		msgBytes, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		return b.Put(itob(msg.ID), msgBytes)
+	})
+	if err != nil {
+		log.Printf("Error saving queued message %v: %v\n", msg.ID, err)
+	}
+}
@@ -0,0 +1,159 @@
+package remind
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/elimisteve/do_reminder/twilhelp"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestOutbox returns an Outbox that "sends" with the returned
+// FakeSender, and which uses a FakeClock
+func newTestOutbox(t *testing.T) (*Outbox, *FakeClock, *twilhelp.FakeSender) {
+	clk := NewFakeClock(time.Date(2026, 6, 1, 8, 0, 0, 0, LosAngeles))
+	prev := SetClock(clk)
+	t.Cleanup(func() { SetClock(prev) })
+
+	sent := twilhelp.NewFakeSender()
+	return NewOutbox(openTestDB(t), sent), clk, sent
+}
+
+func TestOutboxRetries(t *testing.T) {
+	o, clk, sent := newTestOutbox(t)
+	ctx := context.Background()
+
+	sent.FailWith(errors.New("Twilio is down"))
+	id, err := o.Send(ctx, "+15555550100", "Hello")
+	assert.NoError(t, err)
+	assert.Equal(t, twilhelp.MessageID("outbox-1"), id)
+
+	// Waits 30s, then 1m, then 2m
+	for _, backoff := range []time.Duration{30 * time.Second, time.Minute,
+		2 * time.Minute} {
+		assert.Equal(t, backoff, o.sendDue())
+		clk.Advance(backoff - time.Second)
+		assert.Equal(t, time.Second, o.sendDue())
+		clk.Advance(time.Second)
+	}
+
+	pending, err := o.Pending()
+	if assert.NoError(t, err) && assert.Len(t, pending, 1) {
+		assert.Equal(t, 3, pending[0].Attempts)
+		assert.Equal(t, "Twilio is down", pending[0].LastError)
+	}
+
+	sent.FailWith(nil)
+	assert.Equal(t, time.Hour, o.sendDue())
+	if assert.Len(t, sent.Sent(), 1) {
+		assert.Equal(t, "Hello", sent.Last().Body)
+	}
+
+	pending, err = o.Pending()
+	assert.NoError(t, err)
+	assert.Len(t, pending, 0)
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	o := NewOutbox(nil, nil)
+	o.Backoff = time.Minute
+	o.MaxBackoff = 10 * time.Minute
+
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute,
+		8 * time.Minute, 10 * time.Minute, 10 * time.Minute}
+	for i, w := range want {
+		assert.Equal(t, w, o.backoff(i+1))
+	}
+	assert.Equal(t, 10*time.Minute, o.backoff(100))
+}
+
+func TestOutboxGivesUp(t *testing.T) {
+	o, clk, sent := newTestOutbox(t)
+	o.MaxAttempts = 3
+	sent.FailWith(errors.New("Invalid number"))
+
+	r := &Reminder{ID: 7, Recipient: "+15555550100", Description: "Test"}
+	_, err := r.Send(context.Background(), o)
+	assert.NoError(t, err)
+	_, err = o.Send(context.Background(), "+15555550100", "A reply")
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		clk.Advance(o.sendDue())
+	}
+
+	pending, err := o.Pending()
+	assert.NoError(t, err)
+	assert.Len(t, pending, 0)
+
+	failed, err := FailedMessages(o.db, r.ID)
+	if assert.NoError(t, err) && assert.Len(t, failed, 1) {
+		assert.Equal(t, r.ID, failed[0].ReminderID)
+		assert.Equal(t, "Reminder 7: Test", failed[0].Body)
+		assert.Equal(t, 3, failed[0].Attempts)
+		assert.Equal(t, "Invalid number", failed[0].LastError)
+	}
+
+	failed, err = FailedMessages(o.db, 8)
+	assert.NoError(t, err)
+	assert.Len(t, failed, 0)
+
+	// Even behind another Sender
+	r.ID = 8
+	_, err = r.Send(context.Background(), wrappedSender{o})
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		clk.Advance(o.sendDue())
+	}
+	failed, err = FailedMessages(o.db, 8)
+	if assert.NoError(t, err) && assert.Len(t, failed, 1) {
+		assert.Equal(t, "Reminder 8: Test", failed[0].Body)
+	}
+}
+
+// wrappedSender wraps a Sender, as a decorator might
+type wrappedSender struct {
+	twilhelp.Sender
+}
+
+func (l wrappedSender) Send(ctx context.Context, to, body string) (twilhelp.MessageID, error) {
+	return l.Sender.Send(ctx, to, body)
+}
+
+func TestOutboxRun(t *testing.T) {
+	o, clk, sent := newTestOutbox(t)
+	o.Start()
+	defer o.Stop()
+
+	// Sent as soon as it's queued
+	_, err := o.Send(context.Background(), "+15555550100", "First")
+	assert.NoError(t, err)
+	_, ok := sent.WaitFor(1, 5*time.Second)
+	assert.True(t, ok)
+
+	// Retried once the backoff is up
+	sent.FailWith(errors.New("Twilio is down"))
+	_, err = o.Send(context.Background(), "+15555550100", "Second")
+	assert.NoError(t, err)
+
+	for {
+		pending, err := o.Pending()
+		if !assert.NoError(t, err) {
+			return
+		}
+		if len(pending) == 1 && pending[0].Attempts == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	sent.FailWith(nil)
+	clk.BlockUntil(1)
+	clk.Advance(o.Backoff)
+
+	msgs, ok := sent.WaitFor(2, 5*time.Second)
+	if assert.True(t, ok) {
+		assert.Equal(t, "Second", msgs[1].Body)
+	}
+}
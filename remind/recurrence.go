@@ -0,0 +1,332 @@
+package remind
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	Weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday,
+		time.Thursday, time.Friday}
+	Weekends = []time.Weekday{time.Saturday, time.Sunday}
+)
+
+type Frequency string
+
+const (
+	Weekly  Frequency = "weekly" // Default
+	Monthly Frequency = "monthly"
+	Yearly  Frequency = "yearly"
+)
+
+// Recurrence describes a reminder that repeats on certain days of the
+// calendar (at NextRun's time of day) rather than every Period.
+type Recurrence struct {
+	Freq Frequency `json:",omitempty"`
+
+	// Weekdays the reminder runs on. Empty means NextRun's weekday
+	// (i.e., weekly). For Monthly recurrences with Nth set, only
+	// Weekdays[0] is used.
+	Weekdays []time.Weekday `json:",omitempty"`
+
+	// Weeks, months, or years between runs, depending on Freq; 0 and
+	// 1 both mean every week/month/year
+	Interval int `json:",omitempty"`
+
+	// Day of the month to run on for Monthly and Yearly recurrences,
+	// where -1 means the last day. Clamped to the end of shorter
+	// months (e.g., the 31st runs on Feb 28).
+	MonthDay int `json:",omitempty"`
+
+	// Runs on the Nth Weekdays[0] of the month, e.g. 1 for the first
+	// Monday, or -1 for the last Friday
+	Nth int `json:",omitempty"`
+
+	// Month to run in for Yearly recurrences
+	Month time.Month `json:",omitempty"`
+}
+
+func (rec *Recurrence) freq() Frequency {
+	if rec.Freq == "" {
+		return Weekly
+	}
+	return rec.Freq
+}
+
+func (rec *Recurrence) interval() int {
+	if rec.Interval < 1 {
+		return 1
+	}
+	return rec.Interval
+}
+
+// Next returns the first run after prev, at prev's time of day in
+// prev's location, counting weeks between runs from prev's.
+func (rec *Recurrence) Next(prev time.Time) time.Time {
+	return rec.nextAt(prev, prev, clockOf(prev))
+}
+
+// nextAt is like Next, but runs at the time of day at rather than
+// prev's, e.g. so 02:30 reminders moved to 03:30 by daylight saving
+// time go back to 02:30 the next time, and counts weeks between runs
+// from start's.
+func (rec *Recurrence) nextAt(start, prev time.Time, at timeOfDay) time.Time {
+	switch rec.freq() {
+	case Monthly:
+		return rec.nextByMonth(prev, rec.interval(), at)
+	case Yearly:
+		return rec.nextByMonth(prev, 12*rec.interval(), at)
+	}
+	return rec.nextByWeek(start, prev, at)
+}
+
+// timeOfDay is a local time of day
+type timeOfDay struct {
+	hour, min, sec int
+}
+
+func clockOf(t time.Time) timeOfDay {
+	hour, min, sec := t.Clock()
+	return timeOfDay{hour, min, sec}
+}
+
+// on returns c on the given day, which is normalized like time.Date's
+func (c timeOfDay) on(year int, month time.Month, day int, loc *time.Location) time.Time {
+	return WallClock(year, month, day, c.hour, c.min, c.sec, loc)
+}
+
+// nextByWeek returns the first run after prev in a week that's a
+// multiple of rec's interval after start's. Weeks start on Monday, as
+// in RFC 5545, so weekends aren't split across two.
+func (rec *Recurrence) nextByWeek(start, prev time.Time, at timeOfDay) time.Time {
+	interval := rec.interval()
+
+	weekdays := rec.Weekdays
+	if len(weekdays) == 0 {
+		weekdays = []time.Weekday{prev.Weekday()}
+	}
+
+	year, month, day := prev.Date()
+	startWeek := mondayWeek(start)
+
+	for i := 1; i <= 7*(interval+1); i++ {
+		next := at.on(year, month, day+i, prev.Location())
+
+		// Skip the weeks in between, e.g. for "every other Monday"
+		if week := mondayWeek(next) - startWeek; week%interval != 0 {
+			continue
+		}
+
+		for _, wd := range weekdays {
+			if next.Weekday() == wd {
+				return next
+			}
+		}
+	}
+
+	// Unreachable unless weekdays contains an invalid time.Weekday
+	return prev.AddDate(0, 0, 7*interval)
+}
+
+// mondayWeek numbers the Monday-to-Sunday week t's date falls in
+func mondayWeek(t time.Time) int {
+	year, month, day := t.Date()
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+
+	// Days since Monday, Dec 29, 1969, the start of the week Jan 1,
+	// 1970 fell in
+	days := int(date.Unix()/(24*60*60)) + 3
+	if days < 0 {
+		return (days - 6) / 7
+	}
+	return days / 7
+}
+
+// nextByMonth returns the first run after prev, trying every
+// monthStep months starting with prev's month (or rec.Month's, for
+// yearly recurrences)
+func (rec *Recurrence) nextByMonth(prev time.Time, monthStep int, at timeOfDay) time.Time {
+	year, month, _ := prev.Date()
+	if rec.freq() == Yearly && rec.Month != 0 {
+		month = rec.Month
+	}
+
+	// Months without a 5th Tuesday, etc, are skipped, so this is
+	// more than enough tries
+	for i := 0; i < 60; i++ {
+		next, ok := rec.dayIn(year, month+time.Month(i*monthStep), prev, at)
+		if ok && next.After(prev) {
+			return next
+		}
+	}
+
+	return prev.AddDate(0, monthStep, 0)
+}
+
+// dayIn returns rec's day in the given month, at the time of day at.
+// ok is false if rec skips that month.
+func (rec *Recurrence) dayIn(year int, month time.Month, prev time.Time, at timeOfDay) (t time.Time, ok bool) {
+	// Normalize, e.g. month 14 of this year to month 2 of the next
+	first := time.Date(year, month, 1, 0, 0, 0, 0, prev.Location())
+	year, month = first.Year(), first.Month()
+	last := daysIn(year, month)
+
+	var day int
+
+	if rec.Nth != 0 && len(rec.Weekdays) > 0 {
+		wd := rec.Weekdays[0]
+		if rec.Nth > 0 {
+			day = 1 + (int(wd)-int(first.Weekday())+7)%7 + 7*(rec.Nth-1)
+		} else {
+			lastWeekday := time.Date(year, month, last, 0, 0, 0, 0,
+				prev.Location()).Weekday()
+			day = last - (int(lastWeekday)-int(wd)+7)%7 + 7*(rec.Nth+1)
+		}
+		if day < 1 || day > last {
+			return time.Time{}, false
+		}
+	} else {
+		day = rec.MonthDay
+		if day == 0 {
+			day = prev.Day()
+		}
+		if day < 0 || day > last {
+			day = last
+		}
+	}
+
+	return at.on(year, month, day, prev.Location()), true
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// Includes reports whether t falls on one of rec's days.
+func (rec *Recurrence) Includes(t time.Time) bool {
+	switch rec.freq() {
+	case Monthly, Yearly:
+		if rec.freq() == Yearly && rec.Month != 0 && t.Month() != rec.Month {
+			return false
+		}
+		day, ok := rec.dayIn(t.Year(), t.Month(), t, clockOf(t))
+		return ok && day.Day() == t.Day()
+	}
+
+	if len(rec.Weekdays) == 0 {
+		return true
+	}
+	for _, wd := range rec.Weekdays {
+		if t.Weekday() == wd {
+			return true
+		}
+	}
+	return false
+}
+
+func (rec *Recurrence) String() string {
+	if rec == nil {
+		return ""
+	}
+
+	switch rec.freq() {
+	case Monthly:
+		return rec.every("month") + " on the " + rec.monthDayString()
+	case Yearly:
+		on := ""
+		if rec.Month != 0 {
+			on = " on " + rec.Month.String()[:3] + " " + rec.monthDayString()
+		}
+		return rec.every("year") + on
+	}
+
+	every := "every "
+	if rec.Interval == 2 {
+		every = "every other "
+	} else if rec.Interval > 2 {
+		every = "every " + strconv.Itoa(rec.Interval) + " weeks on "
+	}
+
+	var days string
+	switch {
+	case len(rec.Weekdays) == 0:
+		return rec.every("week")
+	case sameWeekdays(rec.Weekdays, Weekdays):
+		days = "weekday"
+	case sameWeekdays(rec.Weekdays, Weekends):
+		days = "weekend"
+	default:
+		names := make([]string, len(rec.Weekdays))
+		for i, wd := range rec.Weekdays {
+			names[i] = wd.String()[:3]
+		}
+		days = strings.Join(names, ", ")
+	}
+
+	return every + days
+}
+
+// every returns, e.g., "every month", "every other month", or "every 3
+// months"
+func (rec *Recurrence) every(unit string) string {
+	switch interval := rec.interval(); interval {
+	case 1:
+		return "every " + unit
+	case 2:
+		return "every other " + unit
+	default:
+		return fmt.Sprintf("every %d %ss", interval, unit)
+	}
+}
+
+func (rec *Recurrence) monthDayString() string {
+	if rec.Nth != 0 && len(rec.Weekdays) > 0 {
+		nth := "last"
+		if rec.Nth > 0 {
+			nth = Ordinal(rec.Nth)
+		}
+		return nth + " " + rec.Weekdays[0].String()[:3]
+	}
+	if rec.MonthDay < 0 {
+		return "last day"
+	}
+	return Ordinal(rec.MonthDay)
+}
+
+// Ordinal returns n followed by st, nd, rd, or th
+func Ordinal(n int) string {
+	suffix := "th"
+	switch n % 10 {
+	case 1:
+		suffix = "st"
+	case 2:
+		suffix = "nd"
+	case 3:
+		suffix = "rd"
+	}
+	if n%100 >= 11 && n%100 <= 13 {
+		suffix = "th"
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+func sameWeekdays(a, b []time.Weekday) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, wd := range a {
+		found := false
+		for _, wd2 := range b {
+			if wd == wd2 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,259 @@
+package remind
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecurrenceNext(t *testing.T) {
+	// Sunday
+	sun := time.Date(2026, 10, 18, 9, 30, 0, 0, LosAngeles)
+
+	tests := []struct {
+		rec  Recurrence
+		prev time.Time
+		next []time.Time
+	}{
+		{
+			Recurrence{Weekdays: []time.Weekday{time.Monday}},
+			sun,
+			[]time.Time{
+				sun.AddDate(0, 0, 1),
+				sun.AddDate(0, 0, 8),
+			},
+		},
+		{
+			Recurrence{Weekdays: Weekdays},
+			sun.AddDate(0, 0, 4), // Thursday
+			[]time.Time{
+				sun.AddDate(0, 0, 5),
+				sun.AddDate(0, 0, 8),
+				sun.AddDate(0, 0, 9),
+			},
+		},
+		{
+			Recurrence{Weekdays: []time.Weekday{time.Tuesday, time.Thursday}},
+			sun.AddDate(0, 0, 2), // Tuesday
+			[]time.Time{
+				sun.AddDate(0, 0, 4),
+				sun.AddDate(0, 0, 9),
+				sun.AddDate(0, 0, 11),
+			},
+		},
+		{
+			// Weekly on prev's weekday
+			Recurrence{},
+			sun,
+			[]time.Time{
+				sun.AddDate(0, 0, 7),
+				sun.AddDate(0, 0, 14),
+			},
+		},
+		{
+			// Every other Monday and Friday
+			Recurrence{Weekdays: []time.Weekday{time.Monday, time.Friday},
+				Interval: 2},
+			sun.AddDate(0, 0, 1), // Monday
+			[]time.Time{
+				sun.AddDate(0, 0, 5),
+				sun.AddDate(0, 0, 15),
+				sun.AddDate(0, 0, 19),
+			},
+		},
+		{
+			// Wall-clock time is kept across the end of DST (Nov 1)
+			Recurrence{Weekdays: []time.Weekday{time.Sunday}},
+			sun.AddDate(0, 0, 7),
+			[]time.Time{
+				time.Date(2026, 11, 1, 9, 30, 0, 0, LosAngeles),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		prev := test.prev
+		for _, want := range test.next {
+			got := test.rec.Next(prev)
+			assert.Equal(t, want, got, "Wrong next run for %s after %s",
+				test.rec.String(), prev)
+			prev = got
+		}
+	}
+}
+
+func TestRecurrenceEveryOtherWeekend(t *testing.T) {
+	at := func(month time.Month, day int) time.Time {
+		return time.Date(2026, month, day, 10, 0, 0, 0, LosAngeles)
+	}
+
+	// Saturday
+	start := at(6, 6)
+	r := &Reminder{NextRun: start, Start: start,
+		Recurrence: &Recurrence{Weekdays: Weekends, Interval: 2}}
+
+	// Both days of every other weekend
+	want := []time.Time{at(6, 7), at(6, 20), at(6, 21), at(7, 4), at(7, 5)}
+	prev := start
+	for _, w := range want {
+		next, ok := r.next(prev)
+		assert.True(t, ok)
+		assert.Equal(t, w, next)
+		prev = next
+	}
+
+	// Counting from Start's week, not prev's
+	next, _ := r.next(at(6, 10))
+	assert.Equal(t, at(6, 20), next)
+	next, _ = r.next(at(6, 14))
+	assert.Equal(t, at(6, 20), next)
+}
+
+func TestMondayWeek(t *testing.T) {
+	mon := time.Date(2026, 6, 1, 0, 0, 0, 0, LosAngeles)
+	sun := time.Date(2026, 6, 7, 23, 59, 0, 0, LosAngeles)
+	assert.Equal(t, mondayWeek(mon), mondayWeek(sun))
+	assert.Equal(t, mondayWeek(mon)+1, mondayWeek(sun.AddDate(0, 0, 1)))
+
+	// Before 1970, too
+	assert.Equal(t, 0, mondayWeek(time.Date(1969, 12, 29, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, -1, mondayWeek(time.Date(1969, 12, 28, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, -1, mondayWeek(time.Date(1969, 12, 22, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestRecurrenceNextCalendar(t *testing.T) {
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 8, 0, 0, 0, LosAngeles)
+	}
+
+	tests := []struct {
+		rec  Recurrence
+		prev time.Time
+		next []time.Time
+	}{
+		{
+			// 1st of every month
+			Recurrence{Freq: Monthly, MonthDay: 1},
+			at(2026, 10, 18),
+			[]time.Time{at(2026, 11, 1), at(2026, 12, 1), at(2027, 1, 1)},
+		},
+		{
+			// 31st, clamped to the end of shorter months
+			Recurrence{Freq: Monthly, MonthDay: 31},
+			at(2027, 1, 2),
+			[]time.Time{at(2027, 1, 31), at(2027, 2, 28), at(2027, 3, 31),
+				at(2027, 4, 30)},
+		},
+		{
+			// Last day of the month, including leap years
+			Recurrence{Freq: Monthly, MonthDay: -1},
+			at(2028, 1, 31),
+			[]time.Time{at(2028, 2, 29), at(2028, 3, 31)},
+		},
+		{
+			// Last Friday of the month
+			Recurrence{Freq: Monthly, Nth: -1,
+				Weekdays: []time.Weekday{time.Friday}},
+			at(2026, 10, 1),
+			[]time.Time{at(2026, 10, 30), at(2026, 11, 27), at(2026, 12, 25)},
+		},
+		{
+			// 2nd Tuesday of every other month
+			Recurrence{Freq: Monthly, Nth: 2, Interval: 2,
+				Weekdays: []time.Weekday{time.Tuesday}},
+			at(2026, 10, 13),
+			[]time.Time{at(2026, 12, 8), at(2027, 2, 9)},
+		},
+		{
+			// 5th Thursday, skipping months that don't have one
+			Recurrence{Freq: Monthly, Nth: 5,
+				Weekdays: []time.Weekday{time.Thursday}},
+			at(2026, 10, 18),
+			[]time.Time{at(2026, 10, 29), at(2026, 12, 31), at(2027, 4, 29)},
+		},
+		{
+			// Every March 15
+			Recurrence{Freq: Yearly, Month: time.March, MonthDay: 15},
+			at(2026, 10, 18),
+			[]time.Time{at(2027, 3, 15), at(2028, 3, 15)},
+		},
+		{
+			// Every Feb 29, clamped in non-leap years
+			Recurrence{Freq: Yearly, Month: time.February, MonthDay: 29},
+			at(2027, 3, 1),
+			[]time.Time{at(2028, 2, 29), at(2029, 2, 28)},
+		},
+	}
+
+	for _, test := range tests {
+		prev := test.prev
+		for _, want := range test.next {
+			got := test.rec.Next(prev)
+			assert.Equal(t, want, got, "Wrong next run for %s after %s",
+				test.rec.String(), prev)
+			assert.True(t, test.rec.Includes(got), "%s should include %s",
+				test.rec.String(), got)
+			prev = got
+		}
+	}
+}
+
+func TestRecurrenceJSON(t *testing.T) {
+	// Reminders saved before Recurrence existed
+	legacy := `{"ID":0,"Recipient":"+15555555555","Description":"Take out` +
+		` the trash","NextRun":"2016-05-30T18:00:00-07:00","Period":` +
+		`86400000000000,"PlusMinus":0,"Raw":"","Created":` +
+		`"2016-05-29T12:00:00-07:00","Cancelled":false}`
+
+	var r Reminder
+	if err := json.Unmarshal([]byte(legacy), &r); err != nil {
+		t.Fatalf("Error unmarshaling legacy reminder: %v", err)
+	}
+	assert.Nil(t, r.Recurrence)
+	assert.Equal(t, 24*time.Hour, r.Period)
+	assert.Equal(t, "every 24h0m0s", r.Repetition())
+
+	r.Recurrence = &Recurrence{Freq: Monthly, Nth: -1,
+		Weekdays: []time.Weekday{time.Friday}}
+
+	b, err := json.Marshal(&r)
+	if err != nil {
+		t.Fatalf("Error marshaling reminder: %v", err)
+	}
+
+	var r2 Reminder
+	if err := json.Unmarshal(b, &r2); err != nil {
+		t.Fatalf("Error unmarshaling reminder: %v", err)
+	}
+	assert.Equal(t, r.Recurrence, r2.Recurrence)
+	assert.Equal(t, "every month on the last Fri", r2.Repetition())
+}
+
+func TestFutureizeNextRunRecurrence(t *testing.T) {
+	now := Now()
+
+	r := &Reminder{
+		NextRun:    now.AddDate(0, 0, -30),
+		Recurrence: &Recurrence{Weekdays: []time.Weekday{time.Wednesday}},
+	}
+
+	changed, err := r.FutureizeNextRun()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, r.NextRun.After(now), "NextRun should be in the future")
+	assert.True(t, r.NextRun.Before(now.AddDate(0, 0, 7)),
+		"NextRun should be within a week")
+	assert.Equal(t, time.Wednesday, r.NextRun.Weekday())
+
+	r = &Reminder{
+		NextRun: now.Add(-90 * time.Minute),
+		Period:  time.Hour,
+	}
+
+	changed, err = r.FutureizeNextRun()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, now.Add(30*time.Minute), r.NextRun)
+}
@@ -4,6 +4,7 @@
 package remind
 
 import (
+	"context"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
@@ -19,21 +20,99 @@ var (
 	boltBucket = []byte("reminder")
 
 	ErrReminderNotFound = errors.New("Reminder not found")
+	ErrNoMoreRuns       = errors.New("Reminder has no more runs")
+	ErrNotOwner         = errors.New("Reminder belongs to someone else")
+	ErrAlreadyStopped   = errors.New("Reminder already stopped")
 )
 
+// MinPeriod is the shortest Period a reminder may repeat every, so a
+// typo can't text someone every minute
+const MinPeriod = 15 * time.Minute
+
 type Reminder struct {
-	ID          uint64
+	ID uint64
+
+	// Number is r's ID among its recipient's reminders (1, 2, 3, ...),
+	// which is what they see and refer to it by
+	Number uint64 `json:",omitempty"`
+
 	Recipient   string
 	Description string
 	NextRun     time.Time
 	Period      time.Duration // Period == 0 means should only run once
 	PlusMinus   time.Duration
 
+	// Nominal is when the next run is scheduled for, before NextRun
+	// adds up to PlusMinus of jitter to it. Zero means NextRun hasn't
+	// been jittered.
+	Nominal time.Time `json:",omitempty"`
+
+	// IANA timezone name (e.g. "Europe/Berlin") of the recipient, where
+	// r's times of day are interpreted; empty means LosAngeles
+	Timezone string `json:",omitempty"`
+
+	// Recurrence, if set, is used instead of Period
+	Recurrence *Recurrence `json:",omitempty"`
+
+	// RRule, if set, is an RFC 5545 recurrence rule (see ParseRRule)
+	// used instead of Period, with Start as its DTSTART
+	RRule string `json:",omitempty"`
+	rrule *RRule
+
+	// Start is when a repeating reminder first (nominally) runs, which
+	// every later run is derived from. Reminders repeating every Period
+	// run at Start plus a multiple of Period; daily and calendar-based
+	// reminders run at its local time of day, even after daylight
+	// saving time moves a run that'd be skipped.
+	Start time.Time
+
+	// Cron, if set, is a 5-field cron expression (see ParseCron) used
+	// instead of Period, evaluated in r's location
+	Cron string `json:",omitempty"`
+	cron *Cron
+
+	// Until and MaxRuns, if set, end a repeating reminder after that
+	// time or number of runs, at which point it's Completed
+	Until   time.Time
+	MaxRuns int `json:",omitempty"`
+	Runs    int `json:",omitempty"`
+
+	// Missed is what to do about runs missed while the server was
+	// down; empty means DefaultMissed
+	Missed MissedPolicy `json:",omitempty"`
+
+	// Late is how many missed runs are left to send late, starting
+	// with Nominal's
+	Late int `json:",omitempty"`
+
+	// Snoozed, if set, is when r is sent again (once) because its
+	// recipient snoozed it, regardless of when it next runs
+	Snoozed time.Time `json:",omitempty"`
+
+	// NagEvery, if set, means each run must be acknowledged: until
+	// its recipient replies "done", it's sent again every NagEvery, up
+	// to MaxNags (or DefaultMaxNags) times
+	NagEvery time.Duration `json:",omitempty"`
+	MaxNags  int           `json:",omitempty"`
+
+	// Pending is the run waiting to be acknowledged, if any; History,
+	// the runs before it, oldest first (up to MaxHistory of them)
+	Pending *Occurrence   `json:",omitempty"`
+	History []*Occurrence `json:",omitempty"`
+
+	// EscalateTo, if set, are the numbers (e.g. a caregiver's) texted
+	// when a run isn't acknowledged within EscalateAfter (or
+	// DefaultEscalateAfter) of being sent, which call the recipient
+	// RecipientName, if given
+	EscalateTo    []string      `json:",omitempty"`
+	EscalateAfter time.Duration `json:",omitempty"`
+	RecipientName string        `json:",omitempty"`
+
 	Raw     string
 	Created time.Time
 
 	Cancelled bool
-	cancel    chan struct{}
+	Completed bool `json:",omitempty"`
 }
 
 func GetAllReminders(db *bolt.DB) (Reminders, error) {
@@ -53,7 +132,6 @@ func GetAllReminders(db *bolt.DB) (Reminders, error) {
 			}
 
 			rem.ID = binary.BigEndian.Uint64(k)
-			rem.cancel = make(chan struct{})
 
 			allRems = append(allRems, &rem)
 
@@ -64,24 +142,77 @@ func GetAllReminders(db *bolt.DB) (Reminders, error) {
 	return allRems, e
 }
 
-// Schedule reminds r.Recipient to do r.Description starting at
-// r.NextRun, then every r.Period +/- r.PlusMinus after that.
-func (r *Reminder) Schedule(db *bolt.DB) error {
-	if err := r.Check(db); err != nil {
-		return err
+// GetReminder returns the reminder with ID id
+func GetReminder(db *bolt.DB, id uint64) (*Reminder, error) {
+	var rem Reminder
+
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltBucket)
+		if b == nil {
+			return ErrReminderNotFound
+		}
+		v := b.Get(itob(id))
+		if v == nil {
+			return ErrReminderNotFound
+		}
+		return json.Unmarshal(v, &rem)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	log.Printf("Valid Reminder %v scheduled: %s\n", r.ID, r)
+	rem.ID = id
+	return &rem, nil
+}
+
+// GetOwnReminder returns the reminder with ID id, or ErrNotOwner if
+// owner isn't its recipient. Commands that change a reminder should
+// get it this way.
+func GetOwnReminder(db *bolt.DB, owner string, id uint64) (*Reminder, error) {
+	r, err := GetReminder(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if r.Recipient != owner {
+		return nil, ErrNotOwner
+	}
+	return r, nil
+}
 
-	go func() {
-		if err := r.RunAndLoop(db); err != nil {
-			log.Printf("Error running looping reminder %v: %v\n", r.ID, err)
-			return
+// GetRemindersFor returns the reminders sent to recipient, by Number,
+// looking them up by their Numbers rather than reading every reminder.
+// Those saved before Numbers existed are left out until
+// NumberReminders is run.
+func GetRemindersFor(db *bolt.DB, recipient string) (Reminders, error) {
+	var rems Reminders
+
+	err := db.View(func(tx *bolt.Tx) error {
+		numbers := tx.Bucket(numberBucket)
+		b := tx.Bucket(boltBucket)
+		if numbers == nil || b == nil {
+			return nil
+		}
+		theirs := numbers.Bucket([]byte(recipient))
+		if theirs == nil {
+			return nil
 		}
-		log.Printf("Reminder %v stopped looping (no error)\n", r.ID)
-	}()
 
-	return nil
+		return theirs.ForEach(func(_, idBytes []byte) error {
+			v := b.Get(idBytes)
+			if v == nil {
+				return nil
+			}
+			var rem Reminder
+			if err := json.Unmarshal(v, &rem); err != nil {
+				return err
+			}
+			rem.ID = binary.BigEndian.Uint64(idBytes)
+			rems = append(rems, &rem)
+			return nil
+		})
+	})
+
+	return rems, err
 }
 
 func (r *Reminder) Check(db *bolt.DB) error {
@@ -91,24 +222,75 @@ func (r *Reminder) Check(db *bolt.DB) error {
 	if r.Cancelled {
 		return fmt.Errorf("Reminder %v already cancelled", r.ID)
 	}
-	if r.cancel == nil {
-		r.cancel = make(chan struct{})
+	if r.Completed {
+		return fmt.Errorf("Reminder %v already completed", r.ID)
 	}
-
 	if r.Period < 0 {
 		return fmt.Errorf("Reminder cannot have negative period (%v)", r.Period)
 	}
-	if r.Period == 0 {
+	if r.Period != 0 && r.Period < MinPeriod {
+		return fmt.Errorf("Reminder cannot repeat more often than every %v"+
+			" (period %v)", MinPeriod, r.Period)
+	}
+	if r.EscalateAfter < 0 {
+		return fmt.Errorf("Reminder cannot escalate after a negative time"+
+			" (%v)", r.EscalateAfter)
+	}
+	if r.NagEvery != 0 && r.NagEvery < MinNagEvery {
+		return fmt.Errorf("Reminder cannot be sent again more often than"+
+			" every %v (every %v)", MinNagEvery, r.NagEvery)
+	}
+
+	// New reminders, and those saved before Start and Nominal were
+	// added, get them filled in here
+	changed := false
+	if r.Nominal.IsZero() {
+		r.setNext(r.NextRun)
+		changed = true
+	}
+	if r.Repeats() && r.Start.IsZero() {
+		r.Start = r.Nominal
+		changed = true
+	}
+
+	if !r.Repeats() {
+		now := Now()
+		if r.Nominal.After(now) || r.NextRun.After(now) || r.Late > 0 {
+			if changed {
+				return r.Update(db)
+			}
+			return nil
+		}
+		if r.missedPolicy() != MissedSkip {
+			log.Printf("Reminder %v's only run already passed; sending it"+
+				" late\n", r.ID)
+			r.Late = 1
+			return r.Update(db)
+		}
 		log.Printf("Reminder %v's next run already passed, should have"+
 			" only run once; returning nil\n", r.ID)
 		r.Cancelled = true
 		return r.Update(db)
 	}
-	changed, err := r.FutureizeNextRun()
+	if err := r.checkSchedule(); err != nil {
+		return err
+	}
+	futurized := r.catchUp()
+	var err error
+	if r.Late == 0 {
+		futurized, err = r.FutureizeNextRun()
+	}
+	if err == ErrNoMoreRuns || r.done() {
+		log.Printf("Reminder %v has no more runs; completing\n", r.ID)
+		if err := r.complete(db); err != nil {
+			return err
+		}
+		return fmt.Errorf("Reminder %v already finished", r.ID)
+	}
 	if err != nil {
 		return err
 	}
-	if changed {
+	if changed || futurized {
 		if err := r.Update(db); err != nil {
 			return err
 		}
@@ -116,107 +298,373 @@ func (r *Reminder) Check(db *bolt.DB) error {
 	return nil
 }
 
-func (r *Reminder) RunAndLoop(db *bolt.DB) error {
-	r.NextRun = r.NextRun.Add(RandDuration(r.PlusMinus))
-	if r.PlusMinus != 0 {
-		if err := r.Update(db); err != nil {
-			return fmt.Errorf("Error updating reminder: %v", err)
-		}
+// checkSchedule returns an error if r's RRULE or cron expression is
+// invalid, or would run more often than every MinPeriod
+func (r *Reminder) checkSchedule() error {
+	rule, err := r.rule()
+	if err != nil {
+		return err
 	}
+	if rule != nil && rule.minGap(r.Start) < MinPeriod {
+		return fmt.Errorf("Reminder cannot repeat more often than every %v"+
+			" (RRULE %v)", MinPeriod, r.RRule)
+	}
+	c, err := r.cronSchedule()
+	if err != nil {
+		return err
+	}
+	if c != nil && c.minGap() < MinPeriod {
+		return fmt.Errorf("Reminder cannot repeat more often than every %v"+
+			" (cron %v)", MinPeriod, r.Cron)
+	}
+	return nil
+}
 
-	// Sleep till the next run is here
-	dur := max(r.NextRun.Sub(Now()), 0)
-
-	log.Printf("Reminder %v waiting %s before next run\n", r.ID, dur)
+// advance records that r just ran (unsuccessfully if sendErr isn't
+// nil) and schedules its next run, leaving saving it to the caller.
+// more is false if it has no more.
+func (r *Reminder) advance(sendErr error) (more bool, err error) {
+	r.Runs++
+	if r.Late > 0 {
+		r.Late--
+	}
 
-	if r.cancel == nil {
-		r.cancel = make(chan struct{})
+	if !r.Repeats() {
+		if sendErr != nil {
+			log.Printf("PROBLEM: Reminder %v should only send once, but "+
+				"failed to send; erroring out, not trying again\n", r.ID)
+			return false, sendErr
+		}
+		log.Printf("Reminder %v successfully ran once; exiting\n", r.ID)
+		r.Completed = true
+		return false, nil
 	}
 
-	select {
-	case <-r.cancel:
-		log.Printf("Reminder %v cancelled; returning\n", r.ID)
-		return nil
-	case <-time.After(dur):
-		// Keep going
+	if r.MaxRuns != 0 && r.Runs >= r.MaxRuns {
+		log.Printf("Reminder %v ran all %d times; exiting\n", r.ID,
+			r.MaxRuns)
+		r.Completed = true
+		return false, nil
 	}
 
-	for {
-		log.Printf("Texting `%s` to remind him/her to `%s` starting now then"+
-			" every %s +/- within %s after that\n",
-			r.Recipient, r.Description, r.Period, r.PlusMinus)
+	// Each run is derived from the schedule, not from when the last
+	// one happened to be sent, so jitter doesn't accumulate
+	nominal, ok := r.next(r.Nominal)
+	if !ok {
+		log.Printf("Reminder %v has no more runs; exiting\n", r.ID)
+		r.Completed = true
+		return false, nil
+	}
+	r.setNext(nominal)
 
-		err := r.SendSMS()
-		if err != nil {
-			log.Printf("Error sending SMS `%v` to `%v`: %v\n", r.Description,
-				r.Recipient, err)
+	if r.done() {
+		log.Printf("Reminder %v's next run would be after %s; exiting\n",
+			r.ID, r.Until)
+		r.Completed = true
+		return false, nil
+	}
+	log.Printf("Text to %s, `%s`, sending again at %s (%s)\n",
+		r.Recipient, r.Description, r.NextRun, r.Repetition())
 
-			// TODO: Return?
-			time.Sleep(1 * time.Second)
-		}
+	return true, nil
+}
 
-		if r.Period == 0 {
-			if err != nil {
-				log.Printf("PROBLEM: Reminder %v should only send once, but "+
-					"failed to send; erroring out, not trying again\n", r.ID)
-				return err
-			}
-			log.Printf("Reminder %v successfully ran once; exiting\n", r.ID)
-			r.Cancelled = true
-			return r.Update(db)
-		}
+// setNext schedules r's next run for nominal, plus or minus up to
+// r.PlusMinus
+func (r *Reminder) setNext(nominal time.Time) {
+	r.Nominal = nominal
+	r.NextRun = nominal.Add(RandDuration(r.PlusMinus))
+}
 
-		// TODO: Prevent drift. Right now there's nothing stopping
-		// the time at which a reminder runs from drifting 60 mins
-		// every single time!
-		sleep := r.Period + RandDuration(r.PlusMinus)
-		log.Printf("Text to %s, `%s`, sending again in %s (period: %s)\n",
-			r.Recipient, r.Description, sleep, r.Period)
+// Send texts r to its recipient using sender
+func (r *Reminder) Send(ctx context.Context, sender twilhelp.Sender) (twilhelp.MessageID, error) {
+	return r.sendTo(ctx, sender, r.Recipient, r.Message())
+}
 
-		r.NextRun = Now().Add(sleep)
-		if err := r.Update(db); err != nil {
-			return err
-		}
+// sendTo texts body about r to to using sender
+func (r *Reminder) sendTo(ctx context.Context, sender twilhelp.Sender, to, body string) (twilhelp.MessageID, error) {
+	// So an Outbox, even behind another Sender, knows whose message
+	// failed, if it does
+	return sender.Send(withReminderID(ctx, r.ID), to, body)
+}
 
-		select {
-		case <-r.cancel:
-			log.Printf("Reminder %v cancelled; returning\n", r.ID)
-			return nil
-		case <-time.After(max(sleep, -sleep)):
-			// Keep going
+// Message returns the text r sends
+func (r *Reminder) Message() string {
+	msg := r.Description
+	if r.Late > 0 {
+		msg += " (late)"
+	}
+	if r.Num() == 0 {
+		if r.MustAck() {
+			msg += ` (reply "done" when done)`
 		}
+		return msg
+	}
+	if r.MustAck() {
+		msg += fmt.Sprintf(` (reply "done %v" when done)`, r.Num())
 	}
+	return fmt.Sprintf("Reminder %v: %s", r.Num(), msg)
 }
 
-func (r *Reminder) SendSMS() error {
-	prefix := ""
-	if r.ID != 0 {
-		prefix = fmt.Sprintf("Reminder %v: ", r.ID)
+// Num returns r's Number, or for reminders without one, its ID
+func (r *Reminder) Num() uint64 {
+	if r.Number != 0 {
+		return r.Number
 	}
-	return twilhelp.SendSMS(r.Recipient, prefix+r.Description)
+	return r.ID
 }
 
-// Set r.NextRun to be in the future
+// Set r.Nominal (and r.NextRun) to be in the future
 func (r *Reminder) FutureizeNextRun() (changed bool, err error) {
-	if r.Period == 0 {
+	if !r.Repeats() {
 		return false, errors.New("Cannot futurize reminder with a period of 0")
 	}
 	now := Now()
-	if r.NextRun.After(now) {
+	nominal := r.Nominal
+	if nominal.IsZero() {
+		nominal = r.NextRun
+	}
+	if nominal.After(now) {
 		return false, nil
 	}
-	future := r.Period + now.Sub(r.NextRun)/r.Period
 
-	r.NextRun = r.NextRun.Add(future)
+	// RRULEs, cron expressions, and shorter Periods from Start can
+	// skip straight to now; the others need a previous run
+	next := nominal
+	if r.RRule != "" || r.Cron != "" || (!r.wallClock() && !r.Start.IsZero()) {
+		next = now
+	}
+	for !next.After(now) {
+		var ok bool
+		if next, ok = r.next(next); !ok {
+			return false, ErrNoMoreRuns
+		}
+	}
+	r.setNext(next)
 
 	return true, nil
 }
 
-func max(n, m time.Duration) time.Duration {
-	if n > m {
-		return n
+// done reports whether r has run MaxRuns times, or its next run would
+// be after Until
+func (r *Reminder) done() bool {
+	if r.MaxRuns != 0 && r.Runs >= r.MaxRuns {
+		return true
+	}
+	nominal := r.Nominal
+	if nominal.IsZero() {
+		nominal = r.NextRun
+	}
+	return !r.Until.IsZero() && nominal.After(r.Until)
+}
+
+// complete marks r as having finished all of its runs
+func (r *Reminder) complete(db *bolt.DB) error {
+	r.Completed = true
+	return r.Update(db)
+}
+
+// Repeats reports whether r runs more than once
+func (r *Reminder) Repeats() bool {
+	return r.Period != 0 || r.Recurrence != nil || r.RRule != "" ||
+		r.Cron != ""
+}
+
+// next returns the run following prev, or false if r has no more runs
+func (r *Reminder) next(prev time.Time) (time.Time, bool) {
+	switch {
+	case r.Cron != "":
+		c, err := r.cronSchedule()
+		if err != nil {
+			log.Printf("%v\n", err)
+			return time.Time{}, false
+		}
+		return c.Next(prev.In(r.location()))
+	case r.RRule != "":
+		rule, err := r.rule()
+		if err != nil {
+			log.Printf("Reminder %v has an invalid RRULE: %v\n", r.ID, err)
+			return time.Time{}, false
+		}
+		return rule.After(r.Start.In(r.location()), prev)
+	case r.Recurrence != nil:
+		prev = prev.In(r.location())
+		start := prev
+		if !r.Start.IsZero() {
+			start = r.Start.In(r.location())
+		}
+		return r.Recurrence.nextAt(start, prev, r.timeOfDay(prev)), true
+	case r.Period != 0 && r.Period%oneDay == 0:
+		// Same time of day, even if daylight saving time started or
+		// ended in between
+		prev = prev.In(r.location())
+		year, month, d := prev.Date()
+		return r.timeOfDay(prev).on(year, month, d+int(r.Period/oneDay),
+			prev.Location()), true
+	case r.Period != 0:
+		// The first of Start, Start + Period, Start + 2*Period, ...
+		// after prev
+		if r.Start.IsZero() {
+			return prev.Add(r.Period), true
+		}
+		if prev.Before(r.Start) {
+			return r.Start, true
+		}
+		periods := prev.Sub(r.Start)/r.Period + 1
+		return r.Start.Add(periods * r.Period), true
+	}
+	return time.Time{}, false
+}
+
+const oneDay = 24 * time.Hour
+
+// wallClock reports whether r runs at a certain local time of day
+// (given by next) rather than every Period
+func (r *Reminder) wallClock() bool {
+	return r.Period == 0 || r.Period%oneDay == 0
+}
+
+// timeOfDay returns the local time of day r runs at: Start's, or for
+// reminders saved without a Start, prev's
+func (r *Reminder) timeOfDay(prev time.Time) timeOfDay {
+	if r.Start.IsZero() {
+		return clockOf(prev)
+	}
+	return clockOf(r.Start.In(r.location()))
+}
+
+// SetRRule makes r repeat according to the RFC 5545 RRULE rule,
+// starting at r.NextRun (DTSTART), which is moved to the rule's first
+// occurrence
+func (r *Reminder) SetRRule(rule string) error {
+	parsed, err := ParseRRule(rule, r.location())
+	if err != nil {
+		return err
+	}
+
+	start := r.NextRun.In(r.location())
+	if gap := parsed.minGap(start); gap < MinPeriod {
+		return fmt.Errorf("RRULE %q repeats as often as every %v;"+
+			" reminders can't repeat more often than every %v", rule, gap,
+			MinPeriod)
+	}
+	first, ok := parsed.After(start, start.Add(-time.Second))
+	if !ok {
+		return errors.New("RRULE never occurs")
 	}
-	return m
+
+	r.RRule = parsed.String()
+	r.rrule = parsed
+	r.Start = start
+	r.NextRun = first
+	r.Period = 0
+	r.Recurrence = nil
+	r.Cron = ""
+
+	return nil
+}
+
+// SetCron makes r run whenever the cron expression expr fires,
+// starting with the first time after r.NextRun (or now, if later)
+func (r *Reminder) SetCron(expr string) error {
+	c, err := ParseCron(expr)
+	if err != nil {
+		return err
+	}
+
+	after := r.NextRun
+	if now := Now(); after.Before(now) {
+		after = now
+	}
+	first, ok := c.Next(after.In(r.location()))
+	if !ok {
+		return fmt.Errorf("Cron expression %q never fires", expr)
+	}
+
+	if gap := c.minGap(); gap < MinPeriod {
+		return fmt.Errorf("Cron expression %q fires as often as every %v;"+
+			" reminders can't repeat more often than every %v", expr, gap,
+			MinPeriod)
+	}
+
+	r.Cron = c.String()
+	r.cron = c
+	r.NextRun = first
+	r.Period = 0
+	r.Recurrence = nil
+	r.RRule = ""
+
+	return nil
+}
+
+// cronSchedule returns r's parsed Cron, or nil if it doesn't have one
+func (r *Reminder) cronSchedule() (*Cron, error) {
+	if r.Cron == "" || r.cron != nil {
+		return r.cron, nil
+	}
+	c, err := ParseCron(r.Cron)
+	if err != nil {
+		return nil, fmt.Errorf("Reminder %v has an invalid cron expression:"+
+			" %v", r.ID, err)
+	}
+	r.cron = c
+	return c, nil
+}
+
+// rule returns r's parsed RRule, or nil if it doesn't have one
+func (r *Reminder) rule() (*RRule, error) {
+	if r.RRule == "" || r.rrule != nil {
+		return r.rrule, nil
+	}
+	rule, err := ParseRRule(r.RRule, r.location())
+	if err != nil {
+		return nil, fmt.Errorf("Reminder %v has an invalid RRULE: %v",
+			r.ID, err)
+	}
+	r.rrule = rule
+	return rule, nil
+}
+
+// Repetition describes how often r runs, e.g. "every 24h0m0s" or
+// "every Mon, Thu"
+func (r *Reminder) Repetition() string {
+	switch {
+	case r.Cron != "":
+		return "cron " + r.Cron + r.limits()
+	case r.RRule != "":
+		return "RRULE:" + r.RRule + r.limits()
+	case r.Recurrence != nil:
+		return r.Recurrence.String() + r.limits()
+	case r.Period != 0:
+		return "every " + r.Period.String() + r.limits()
+	}
+	return "once"
+}
+
+// limits describes when r stops repeating, e.g. ", 10 times" or
+// " until Dec 31"
+func (r *Reminder) limits() string {
+	s := ""
+	if r.MaxRuns != 0 {
+		s += fmt.Sprintf(", %d times", r.MaxRuns)
+	}
+	if !r.Until.IsZero() {
+		s += " until " + r.Until.In(r.location()).Format("Jan 2, 2006")
+	}
+	return s
+}
+
+// location is where r's times of day are interpreted
+func (r *Reminder) location() *time.Location {
+	if r.Timezone == "" {
+		return LosAngeles
+	}
+	loc, err := LoadLocation(r.Timezone)
+	if err != nil {
+		log.Printf("Reminder %v has an invalid timezone: %v\n", r.ID, err)
+		return LosAngeles
+	}
+	return loc
 }
 
 func (r *Reminder) Save(db *bolt.DB) error {
@@ -229,6 +677,10 @@ func (r *Reminder) Save(db *bolt.DB) error {
 		id, _ := b.NextSequence()
 		r.ID = id
 
+		if err := r.assignNumber(tx); err != nil {
+			return err
+		}
+
 		rBytes, err := json.Marshal(r)
 		if err != nil {
 			return err
@@ -249,20 +701,22 @@ func (r *Reminder) Update(db *bolt.DB) error {
 
 	log.Printf("Updating reminder %v (%s)\n", r.ID, r.Simple())
 
-	return db.Update(func(tx *bolt.Tx) error {
-		rBytes, err := json.Marshal(r)
-		if err != nil {
-			return err
-		}
-
-		b := tx.Bucket(boltBucket)
+	rBytes, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return putReminder(db, r.ID, rBytes)
+}
 
-		return b.Put(r.IDBytes(), rBytes)
+// putReminder saves rBytes, a JSON-encoded Reminder, as the reminder
+// with ID id
+func putReminder(db *bolt.DB, id uint64, rBytes []byte) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put(itob(id), rBytes)
 	})
 }
 
 func (r *Reminder) Cancel(db *bolt.DB) error {
-	r.cancel <- struct{}{}
 	r.Cancelled = true
 
 	err := r.Update(db)
@@ -290,11 +744,16 @@ func (r *Reminder) String() string {
 	if r == nil {
 		return "<nil>"
 	}
-	return fmt.Sprintf("&Reminder{ID:%v, Recipient:%q, Description:%q,"+
-		" NextRun:%q, Period:%s, PlusMinus:%s, Cancelled:%v,"+
-		" Created:%q, Raw:%q}", r.ID, r.Recipient, r.Description,
-		r.NextRun, r.Period, r.PlusMinus, r.Cancelled,
-		r.Created, r.Raw)
+	return fmt.Sprintf("&Reminder{ID:%v, Number:%v, Recipient:%q,"+
+		" Description:%q, NextRun:%q, Nominal:%q, Timezone:%q, Period:%s,"+
+		" Recurrence:%q, RRule:%q, Cron:%q, PlusMinus:%s, Until:%q,"+
+		" MaxRuns:%d, Runs:%d, Missed:%q, Late:%d, NagEvery:%s,"+
+		" MaxNags:%d, EscalateTo:%q, EscalateAfter:%s, Cancelled:%v,"+
+		" Completed:%v, Created:%q, Raw:%q}", r.ID, r.Number, r.Recipient,
+		r.Description, r.NextRun, r.Nominal, r.Timezone, r.Period,
+		r.Recurrence, r.RRule, r.Cron, r.PlusMinus, r.Until, r.MaxRuns,
+		r.Runs, r.Missed, r.Late, r.NagEvery, r.MaxNags, r.EscalateTo,
+		r.EscalateAfter, r.Cancelled, r.Completed, r.Created, r.Raw)
 }
 
 func (r *Reminder) Simple() string {
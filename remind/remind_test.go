@@ -0,0 +1,218 @@
+package remind
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReminderDone(t *testing.T) {
+	now := Now()
+
+	r := &Reminder{NextRun: now, Period: time.Hour, MaxRuns: 3, Runs: 2}
+	assert.False(t, r.done())
+	r.Runs++
+	assert.True(t, r.done())
+
+	r = &Reminder{NextRun: now, Period: time.Hour, Until: now.Add(time.Hour)}
+	assert.False(t, r.done())
+	r.NextRun = r.NextRun.Add(time.Hour)
+	assert.False(t, r.done())
+	r.NextRun = r.NextRun.Add(time.Hour)
+	assert.True(t, r.done())
+}
+
+func TestNextDailyDST(t *testing.T) {
+	la := LosAngeles
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+
+	tests := []struct {
+		name     string
+		timezone string
+		start    time.Time
+		want     []time.Time
+	}{
+		{
+			"Spring forward",
+			"",
+			time.Date(2026, 3, 7, 18, 0, 0, 0, la),
+			[]time.Time{
+				time.Date(2026, 3, 8, 18, 0, 0, 0, la), // 23 hours later
+				time.Date(2026, 3, 9, 18, 0, 0, 0, la),
+			},
+		},
+		{
+			"Fall back",
+			"",
+			time.Date(2026, 10, 31, 18, 0, 0, 0, la),
+			[]time.Time{
+				time.Date(2026, 11, 1, 18, 0, 0, 0, la), // 25 hours later
+				time.Date(2026, 11, 2, 18, 0, 0, 0, la),
+			},
+		},
+		{
+			"Nonexistent time moves forward, then back",
+			"",
+			time.Date(2026, 3, 7, 2, 30, 0, 0, la),
+			[]time.Time{
+				time.Date(2026, 3, 8, 10, 30, 0, 0, time.UTC), // 03:30 PDT
+				time.Date(2026, 3, 9, 9, 30, 0, 0, time.UTC),  // 02:30 PDT
+			},
+		},
+		{
+			"Ambiguous time runs once, the first time",
+			"",
+			time.Date(2026, 10, 31, 1, 30, 0, 0, la),
+			[]time.Time{
+				time.Date(2026, 11, 1, 8, 30, 0, 0, time.UTC), // 01:30 PDT
+				time.Date(2026, 11, 2, 9, 30, 0, 0, time.UTC), // 01:30 PST
+			},
+		},
+		{
+			"Berlin's nonexistent time",
+			"Europe/Berlin",
+			time.Date(2026, 3, 28, 2, 30, 0, 0, berlin),
+			[]time.Time{
+				time.Date(2026, 3, 29, 1, 30, 0, 0, time.UTC), // 03:30 CEST
+				time.Date(2026, 3, 30, 0, 30, 0, 0, time.UTC), // 02:30 CEST
+			},
+		},
+		{
+			"Berlin's ambiguous time",
+			"Europe/Berlin",
+			time.Date(2026, 10, 24, 2, 30, 0, 0, berlin),
+			[]time.Time{
+				time.Date(2026, 10, 25, 0, 30, 0, 0, time.UTC), // 02:30 CEST
+				time.Date(2026, 10, 26, 1, 30, 0, 0, time.UTC), // 02:30 CET
+			},
+		},
+	}
+
+	for _, test := range tests {
+		r := &Reminder{
+			NextRun:  test.start,
+			Start:    test.start,
+			Period:   24 * time.Hour,
+			Timezone: test.timezone,
+		}
+
+		prev := test.start
+		for _, want := range test.want {
+			next, ok := r.next(prev)
+			if assert.True(t, ok, test.name) {
+				assert.True(t, want.Equal(next), "%s: got %s, want %s",
+					test.name, next, want.In(next.Location()))
+			}
+			prev = next
+		}
+	}
+}
+
+func TestNextWeeklyDST(t *testing.T) {
+	// Every Sunday at 02:30, through the Sunday clocks spring forward
+	start := time.Date(2026, 3, 1, 2, 30, 0, 0, LosAngeles)
+	r := &Reminder{
+		NextRun:    start,
+		Start:      start,
+		Recurrence: &Recurrence{Weekdays: []time.Weekday{time.Sunday}},
+	}
+
+	next, _ := r.next(start)
+	assert.Equal(t, "2026-03-08 03:30 PDT", next.Format("2006-01-02 15:04 MST"))
+	next, _ = r.next(next)
+	assert.Equal(t, "2026-03-15 02:30 PDT", next.Format("2006-01-02 15:04 MST"))
+
+	// Reminders saved without a Start keep the previous run's time
+	r.Start = time.Time{}
+	next, _ = r.next(time.Date(2026, 3, 1, 18, 0, 0, 0, LosAngeles))
+	assert.Equal(t, "2026-03-08 18:00 PDT", next.Format("2006-01-02 15:04 MST"))
+}
+
+func TestNextSubDailyPeriodDST(t *testing.T) {
+	// Shorter periods are elapsed time, not wall clock time
+	start := time.Date(2026, 3, 8, 1, 0, 0, 0, LosAngeles)
+	r := &Reminder{NextRun: start, Start: start, Period: 2 * time.Hour}
+
+	next, _ := r.next(start)
+	assert.Equal(t, "04:00 PDT", next.Format("15:04 MST"))
+}
+
+func TestAnchoredPeriod(t *testing.T) {
+	start := time.Date(2026, 6, 1, 9, 0, 0, 0, LosAngeles)
+	r := &Reminder{
+		NextRun:   start,
+		Start:     start,
+		Period:    2 * time.Hour,
+		PlusMinus: 30 * time.Minute,
+	}
+
+	next, _ := r.next(start.Add(-time.Hour))
+	assert.Equal(t, start, next)
+	next, _ = r.next(start.Add(2*time.Hour + 17*time.Minute))
+	assert.Equal(t, start.Add(4*time.Hour), next)
+
+	// However late or early each run is sent, the schedule doesn't
+	// drift
+	r.setNext(start)
+	for i := 1; i <= 100; i++ {
+		nominal, ok := r.next(r.Nominal)
+		if !assert.True(t, ok) {
+			return
+		}
+		r.setNext(nominal)
+
+		assert.Equal(t, start.Add(time.Duration(i)*r.Period), r.Nominal)
+		assert.InDelta(t, 0, r.NextRun.Sub(r.Nominal), float64(r.PlusMinus))
+	}
+}
+
+func TestFutureizeNextRunAnchored(t *testing.T) {
+	now := Now()
+	start := now.Add(-5*time.Hour - 10*time.Minute)
+
+	r := &Reminder{
+		NextRun:   now.Add(-25 * time.Minute), // Jittered
+		Nominal:   now.Add(-10 * time.Minute),
+		Start:     start,
+		Period:    time.Hour,
+		PlusMinus: 10 * time.Minute,
+	}
+
+	changed, err := r.FutureizeNextRun()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, start.Add(6*time.Hour), r.Nominal)
+	assert.InDelta(t, 0, r.NextRun.Sub(r.Nominal), float64(r.PlusMinus))
+
+	// Still in the future, so nothing to do
+	nominal := r.Nominal
+	changed, err = r.FutureizeNextRun()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, nominal, r.Nominal)
+}
+
+func TestNominalJSON(t *testing.T) {
+	now := Now()
+	r := &Reminder{Start: now, Period: time.Hour, PlusMinus: 5 * time.Minute}
+	r.setNext(now.Add(time.Hour))
+
+	b, err := json.Marshal(r)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var r2 Reminder
+	if assert.NoError(t, json.Unmarshal(b, &r2)) {
+		assert.True(t, r.Nominal.Equal(r2.Nominal))
+		assert.True(t, r.NextRun.Equal(r2.NextRun))
+	}
+
+	// Reminders saved before Nominal existed
+	var legacy Reminder
+	err = json.Unmarshal([]byte(`{"NextRun":"2026-06-01T09:00:00-07:00","Period":3600000000000}`), &legacy)
+	if assert.NoError(t, err) {
+		assert.True(t, legacy.Nominal.IsZero())
+	}
+}
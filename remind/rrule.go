@@ -0,0 +1,546 @@
+package remind
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RRule is the subset of an RFC 5545 (iCalendar) recurrence rule that
+// reminders support: FREQ (DAILY, WEEKLY, MONTHLY, or YEARLY),
+// INTERVAL, COUNT, UNTIL, BYMONTH, BYMONTHDAY, BYDAY, BYHOUR, BYMINUTE,
+// and WKST.
+//
+// Occurrences are counted from the rule's start (DTSTART), whose time
+// of day is used unless BYHOUR or BYMINUTE say otherwise.
+type RRule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByMonth    []time.Month
+	ByMonthDay []int // Negative means from the end of the month
+	ByDay      []NthWeekday
+	ByHour     []int
+	ByMinute   []int
+	WeekStart  time.Weekday
+}
+
+// NthWeekday is an entry of BYDAY, e.g. MO (N == 0), 2TU, or -1FR
+type NthWeekday struct {
+	N       int
+	Weekday time.Weekday
+}
+
+const Daily Frequency = "daily"
+
+var rruleWeekdays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// Give up looking for the next occurrence after this many consecutive
+// days/weeks/months/years without one, e.g. for BYMONTH=2;BYMONTHDAY=30
+const maxEmptyPeriods = 2000
+
+// ParseRRule parses and validates an RRULE such as
+// "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10", with or without the leading
+// "RRULE:". An UNTIL without a trailing Z is interpreted in loc.
+func ParseRRule(s string, loc *time.Location) (*RRule, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 6 && strings.EqualFold(s[:6], "RRULE:") {
+		s = s[6:]
+	}
+
+	rule := &RRule{Interval: 1, WeekStart: time.Monday}
+	seen := map[string]bool{}
+
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+
+		keyVal := strings.SplitN(part, "=", 2)
+		if len(keyVal) != 2 || keyVal[1] == "" {
+			return nil, fmt.Errorf("Invalid RRULE part %q", part)
+		}
+		key, val := strings.ToUpper(keyVal[0]), strings.ToUpper(keyVal[1])
+
+		if seen[key] {
+			return nil, fmt.Errorf("RRULE has more than one %s", key)
+		}
+		seen[key] = true
+
+		var err error
+
+		switch key {
+		case "FREQ":
+			switch val {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				rule.Freq = Frequency(strings.ToLower(val))
+			case "SECONDLY", "MINUTELY", "HOURLY":
+				err = fmt.Errorf("FREQ=%s is too frequent for reminders", val)
+			default:
+				err = fmt.Errorf("Invalid FREQ %q", val)
+			}
+		case "INTERVAL":
+			rule.Interval, err = parseRRuleInt(key, val, 1, 1000)
+		case "COUNT":
+			rule.Count, err = parseRRuleInt(key, val, 1, 100000)
+		case "UNTIL":
+			rule.Until, err = parseRRuleUntil(val, loc)
+		case "BYMONTH":
+			var months []int
+			months, err = parseRRuleInts(key, val, 1, 12, false)
+			for _, m := range months {
+				rule.ByMonth = append(rule.ByMonth, time.Month(m))
+			}
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseRRuleInts(key, val, 1, 31, true)
+		case "BYDAY":
+			rule.ByDay, err = parseRRuleByDay(val)
+		case "BYHOUR":
+			rule.ByHour, err = parseRRuleInts(key, val, 0, 23, false)
+		case "BYMINUTE":
+			rule.ByMinute, err = parseRRuleInts(key, val, 0, 59, false)
+		case "WKST":
+			wd, ok := parseRRuleWeekday(val)
+			if !ok {
+				err = fmt.Errorf("Invalid WKST %q", val)
+			}
+			rule.WeekStart = wd
+		default:
+			err = fmt.Errorf("RRULE %s is not supported", key)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, errors.New("RRULE must include FREQ")
+	}
+	if rule.Count != 0 && !rule.Until.IsZero() {
+		return nil, errors.New("RRULE cannot include both COUNT and UNTIL")
+	}
+	if rule.Freq == Weekly && len(rule.ByMonthDay) != 0 {
+		return nil, errors.New("BYMONTHDAY doesn't work with FREQ=WEEKLY")
+	}
+	for _, day := range rule.ByDay {
+		if day.N == 0 {
+			continue
+		}
+		if rule.Freq != Monthly && rule.Freq != Yearly {
+			return nil, fmt.Errorf("BYDAY=%s only makes sense with"+
+				" FREQ=MONTHLY or FREQ=YEARLY", day)
+		}
+		if rule.Freq == Monthly && (day.N > 5 || day.N < -5) {
+			return nil, fmt.Errorf("Months don't have a %s", day)
+		}
+	}
+
+	return rule, nil
+}
+
+func parseRRuleInt(key, val string, min, max int) (int, error) {
+	n, err := strconv.Atoi(val)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("Invalid %s %q", key, val)
+	}
+	return n, nil
+}
+
+// parseRRuleInts parses a comma-separated list of numbers between min
+// and max (or -max and -min, if negative is true)
+func parseRRuleInts(key, val string, min, max int, negative bool) ([]int, error) {
+	var nums []int
+	for _, s := range strings.Split(val, ",") {
+		n, err := strconv.Atoi(s)
+		abs := n
+		if negative && n < 0 {
+			abs = -n
+		}
+		if err != nil || abs < min || abs > max {
+			return nil, fmt.Errorf("Invalid %s %q", key, s)
+		}
+		nums = append(nums, n)
+	}
+	return nums, nil
+}
+
+func parseRRuleByDay(val string) ([]NthWeekday, error) {
+	var days []NthWeekday
+
+	for _, s := range strings.Split(val, ",") {
+		if len(s) < 2 {
+			return nil, fmt.Errorf("Invalid BYDAY %q", s)
+		}
+
+		wd, ok := parseRRuleWeekday(s[len(s)-2:])
+		if !ok {
+			return nil, fmt.Errorf("Invalid BYDAY %q", s)
+		}
+
+		n := 0
+		if nStr := s[:len(s)-2]; nStr != "" {
+			var err error
+			n, err = strconv.Atoi(strings.TrimPrefix(nStr, "+"))
+			if err != nil || n == 0 || n > 53 || n < -53 {
+				return nil, fmt.Errorf("Invalid BYDAY %q", s)
+			}
+		}
+
+		days = append(days, NthWeekday{N: n, Weekday: wd})
+	}
+
+	return days, nil
+}
+
+func parseRRuleWeekday(s string) (time.Weekday, bool) {
+	for i, name := range rruleWeekdays {
+		if s == name {
+			return time.Weekday(i), true
+		}
+	}
+	return 0, false
+}
+
+func parseRRuleUntil(val string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", val); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102T150405", val, loc); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102", val, loc); err == nil {
+		// Through the end of that day
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("Invalid UNTIL %q", val)
+}
+
+// After returns the first occurrence of rule after t, where start is
+// the rule's DTSTART. ok is false once the rule has no more
+// occurrences (because of COUNT or UNTIL).
+func (rule *RRule) After(start, t time.Time) (next time.Time, ok bool) {
+	count := 0
+	empty := 0
+
+	for period := 0; empty < maxEmptyPeriods; period++ {
+		occurrences := rule.occurrencesIn(start, period)
+		if len(occurrences) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+
+		for _, occ := range occurrences {
+			if occ.Before(start) {
+				continue
+			}
+			if !rule.Until.IsZero() && occ.After(rule.Until) {
+				return time.Time{}, false
+			}
+			count++
+			if rule.Count != 0 && count > rule.Count {
+				return time.Time{}, false
+			}
+			if occ.After(t) {
+				return occ, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// Includes reports whether t is one of rule's occurrences.
+func (rule *RRule) Includes(start, t time.Time) bool {
+	next, ok := rule.After(start, t.Add(-time.Second))
+	return ok && next.Equal(t)
+}
+
+// minGap returns the shortest time between two consecutive
+// occurrences of rule, where start is its DTSTART, not counting
+// daylight saving time changes or COUNT and UNTIL. It looks through
+// as many as minGapPeriods days/weeks/months/years, which covers
+// every combination of BYMONTH and BYDAY short of YEARLY ones.
+func (rule *RRule) minGap(start time.Time) time.Duration {
+	const minGapPeriods = 400
+
+	// Wall clock time, so DST doesn't count
+	y, mo, d := start.Date()
+	start = time.Date(y, mo, d, start.Hour(), start.Minute(),
+		start.Second(), 0, time.UTC)
+
+	gap := time.Duration(1<<63 - 1)
+	var prev time.Time
+	for period := 0; period < minGapPeriods; period++ {
+		for _, occ := range rule.occurrencesIn(start, period) {
+			if !prev.IsZero() && occ.Sub(prev) < gap {
+				gap = occ.Sub(prev)
+			}
+			prev = occ
+		}
+	}
+	return gap
+}
+
+// occurrencesIn returns, in order, the occurrences in the nth
+// day/week/month/year (depending on rule.Freq) counting from start's
+func (rule *RRule) occurrencesIn(start time.Time, n int) []time.Time {
+	year, month, day := start.Date()
+	loc := start.Location()
+	step := n * rule.Interval
+
+	var days []time.Time
+
+	switch rule.Freq {
+	case Daily:
+		d := time.Date(year, month, day+step, 0, 0, 0, 0, loc)
+		if rule.matchesMonth(d) && rule.matchesMonthDay(d) &&
+			rule.matchesWeekday(d) {
+			days = append(days, d)
+		}
+
+	case Weekly:
+		offset := (int(start.Weekday()) - int(rule.WeekStart) + 7) % 7
+		weekStart := time.Date(year, month, day-offset+7*step, 0, 0, 0, 0, loc)
+		for i := 0; i < 7; i++ {
+			d := weekStart.AddDate(0, 0, i)
+			if !rule.matchesMonth(d) {
+				continue
+			}
+			if len(rule.ByDay) == 0 && d.Weekday() != start.Weekday() {
+				continue
+			}
+			if len(rule.ByDay) != 0 && !rule.matchesWeekday(d) {
+				continue
+			}
+			days = append(days, d)
+		}
+
+	case Monthly:
+		first := time.Date(year, month+time.Month(step), 1, 0, 0, 0, 0, loc)
+		if rule.matchesMonth(first) {
+			days = rule.daysInMonth(first, start)
+		}
+
+	case Yearly:
+		y := year + step
+		switch {
+		case len(rule.ByMonth) != 0:
+			for _, m := range rule.ByMonth {
+				first := time.Date(y, m, 1, 0, 0, 0, 0, loc)
+				days = append(days, rule.daysInMonth(first, start)...)
+			}
+		case len(rule.ByMonthDay) != 0:
+			for m := time.January; m <= time.December; m++ {
+				first := time.Date(y, m, 1, 0, 0, 0, 0, loc)
+				days = append(days, rule.daysInMonth(first, start)...)
+			}
+		case len(rule.ByDay) != 0:
+			days = rule.daysInYear(y, loc)
+		default:
+			d := time.Date(y, month, day, 0, 0, 0, 0, loc)
+			if d.Day() == day { // Skip Feb 29 in non-leap years
+				days = append(days, d)
+			}
+		}
+	}
+
+	return rule.atTimes(days, start)
+}
+
+func (rule *RRule) matchesMonth(d time.Time) bool {
+	if len(rule.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range rule.ByMonth {
+		if d.Month() == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (rule *RRule) matchesMonthDay(d time.Time) bool {
+	if len(rule.ByMonthDay) == 0 {
+		return true
+	}
+	last := daysIn(d.Year(), d.Month())
+	for _, md := range rule.ByMonthDay {
+		if md == d.Day() || (md < 0 && last+md+1 == d.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rule *RRule) matchesWeekday(d time.Time) bool {
+	if len(rule.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range rule.ByDay {
+		if wd.Weekday == d.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// daysInMonth returns the days in first's month that match
+// BYMONTHDAY and BYDAY, or start's day of the month if neither is set
+func (rule *RRule) daysInMonth(first, start time.Time) []time.Time {
+	year, month := first.Year(), first.Month()
+	last := daysIn(year, month)
+
+	var days []time.Time
+
+	if len(rule.ByMonthDay) == 0 && len(rule.ByDay) == 0 {
+		if start.Day() <= last {
+			days = append(days, first.AddDate(0, 0, start.Day()-1))
+		}
+		return days
+	}
+
+	for d := 1; d <= last; d++ {
+		day := first.AddDate(0, 0, d-1)
+		if !rule.matchesMonthDay(day) {
+			continue
+		}
+		if len(rule.ByDay) != 0 && !rule.matchesNthWeekday(day, d, last) {
+			continue
+		}
+		days = append(days, day)
+	}
+
+	return days
+}
+
+// matchesNthWeekday reports whether day (the dayNum-th of a month of
+// length last) matches BYDAY, e.g. 2TU for the 2nd Tuesday
+func (rule *RRule) matchesNthWeekday(day time.Time, dayNum, last int) bool {
+	for _, wd := range rule.ByDay {
+		if wd.Weekday != day.Weekday() {
+			continue
+		}
+		switch {
+		case wd.N == 0:
+			return true
+		case wd.N > 0 && (dayNum-1)/7+1 == wd.N:
+			return true
+		case wd.N < 0 && -((last-dayNum)/7+1) == wd.N:
+			return true
+		}
+	}
+	return false
+}
+
+// daysInYear returns the days in year that match BYDAY, where the Nth
+// weekday is counted from the start (or end) of the year
+func (rule *RRule) daysInYear(year int, loc *time.Location) []time.Time {
+	var days []time.Time
+
+	first := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	total := time.Date(year, time.December, 31, 0, 0, 0, 0, loc).YearDay()
+
+	for d := 1; d <= total; d++ {
+		day := first.AddDate(0, 0, d-1)
+		if rule.matchesNthWeekday(day, d, total) {
+			days = append(days, day)
+		}
+	}
+
+	return days
+}
+
+// atTimes returns each of days at each BYHOUR and BYMINUTE (or start's
+// time of day), in order
+func (rule *RRule) atTimes(days []time.Time, start time.Time) []time.Time {
+	hours := rule.ByHour
+	if len(hours) == 0 {
+		hours = []int{start.Hour()}
+	}
+	mins := rule.ByMinute
+	if len(mins) == 0 {
+		mins = []int{start.Minute()}
+	}
+
+	var times []time.Time
+	for _, d := range days {
+		for _, h := range hours {
+			for _, m := range mins {
+				times = append(times, WallClock(d.Year(), d.Month(), d.Day(),
+					h, m, start.Second(), d.Location()))
+			}
+		}
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	return times
+}
+
+func (day NthWeekday) String() string {
+	if day.N == 0 {
+		return rruleWeekdays[day.Weekday]
+	}
+	return strconv.Itoa(day.N) + rruleWeekdays[day.Weekday]
+}
+
+// String returns rule in RRULE form (without the leading "RRULE:")
+func (rule *RRule) String() string {
+	if rule == nil {
+		return ""
+	}
+
+	parts := []string{"FREQ=" + strings.ToUpper(string(rule.Freq))}
+
+	if rule.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(rule.Interval))
+	}
+	if rule.Count != 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(rule.Count))
+	}
+	if !rule.Until.IsZero() {
+		parts = append(parts, "UNTIL="+rule.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(rule.ByMonth) != 0 {
+		months := make([]int, len(rule.ByMonth))
+		for i, m := range rule.ByMonth {
+			months[i] = int(m)
+		}
+		parts = append(parts, "BYMONTH="+joinInts(months))
+	}
+	if len(rule.ByMonthDay) != 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(rule.ByMonthDay))
+	}
+	if len(rule.ByDay) != 0 {
+		days := make([]string, len(rule.ByDay))
+		for i, day := range rule.ByDay {
+			days[i] = day.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(rule.ByHour) != 0 {
+		parts = append(parts, "BYHOUR="+joinInts(rule.ByHour))
+	}
+	if len(rule.ByMinute) != 0 {
+		parts = append(parts, "BYMINUTE="+joinInts(rule.ByMinute))
+	}
+	if rule.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+rruleWeekdays[rule.WeekStart])
+	}
+
+	return strings.Join(parts, ";")
+}
+
+func joinInts(nums []int) string {
+	strs := make([]string, len(nums))
+	for i, n := range nums {
+		strs[i] = strconv.Itoa(n)
+	}
+	return strings.Join(strs, ",")
+}
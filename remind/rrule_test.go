@@ -0,0 +1,279 @@
+package remind
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRRule(t *testing.T) {
+	good := []struct {
+		rule, canonical string
+	}{
+		{"FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10", "FREQ=WEEKLY;COUNT=10;BYDAY=MO,WE"},
+		{"RRULE:freq=daily;interval=2", "FREQ=DAILY;INTERVAL=2"},
+		{"FREQ=MONTHLY;BYDAY=-1FR", "FREQ=MONTHLY;BYDAY=-1FR"},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,-1", "FREQ=MONTHLY;BYMONTHDAY=1,-1"},
+		{"FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=15;UNTIL=20300101T000000Z",
+			"FREQ=YEARLY;UNTIL=20300101T000000Z;BYMONTH=3;BYMONTHDAY=15"},
+		{"FREQ=DAILY;BYHOUR=9,17;BYMINUTE=30;WKST=SU",
+			"FREQ=DAILY;BYHOUR=9,17;BYMINUTE=30;WKST=SU"},
+	}
+
+	for _, test := range good {
+		rule, err := ParseRRule(test.rule, LosAngeles)
+		if err != nil {
+			t.Errorf("Error parsing `%s`: %v", test.rule, err)
+			continue
+		}
+		assert.Equal(t, test.canonical, rule.String())
+	}
+
+	bad := []string{
+		"",
+		"BYDAY=MO",
+		"FREQ=HOURLY",
+		"FREQ=FORTNIGHTLY",
+		"FREQ=WEEKLY;FREQ=DAILY",
+		"FREQ=WEEKLY;COUNT=0",
+		"FREQ=WEEKLY;COUNT=3;UNTIL=20300101",
+		"FREQ=WEEKLY;BYDAY=2MO",
+		"FREQ=MONTHLY;BYDAY=6MO",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=DAILY;BYHOUR=24",
+		"FREQ=DAILY;BYSETPOS=1",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ",
+	}
+
+	for _, rule := range bad {
+		_, err := ParseRRule(rule, LosAngeles)
+		assert.Error(t, err, "Parsing `%s` should fail", rule)
+	}
+}
+
+func TestRRuleAfter(t *testing.T) {
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, LosAngeles)
+	}
+
+	// Sunday
+	start := at(10, 18, 9, 0)
+
+	tests := []struct {
+		rule string
+		next []time.Time
+	}{
+		{
+			"FREQ=WEEKLY;BYDAY=MO,WE;COUNT=3",
+			[]time.Time{at(10, 19, 9, 0), at(10, 21, 9, 0), at(10, 26, 9, 0)},
+		},
+		{
+			"FREQ=DAILY;INTERVAL=2;UNTIL=20261023",
+			[]time.Time{at(10, 18, 9, 0), at(10, 20, 9, 0), at(10, 22, 9, 0)},
+		},
+		{
+			"FREQ=DAILY;BYHOUR=9,17;BYMINUTE=30",
+			[]time.Time{at(10, 18, 9, 30), at(10, 18, 17, 30),
+				at(10, 19, 9, 30)},
+		},
+		{
+			// Sunday the 18th is in the week of Monday the 12th
+			"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU",
+			[]time.Time{at(10, 27, 9, 0), at(11, 10, 9, 0), at(11, 24, 9, 0)},
+		},
+		{
+			"FREQ=MONTHLY;BYDAY=-1FR",
+			[]time.Time{at(10, 30, 9, 0), at(11, 27, 9, 0), at(12, 25, 9, 0)},
+		},
+		{
+			"FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13",
+			[]time.Time{at(11, 13, 9, 0),
+				time.Date(2027, 8, 13, 9, 0, 0, 0, LosAngeles)},
+		},
+		{
+			"FREQ=MONTHLY;BYMONTHDAY=-1",
+			[]time.Time{at(10, 31, 9, 0), at(11, 30, 9, 0), at(12, 31, 9, 0)},
+		},
+		{
+			"FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=15",
+			[]time.Time{time.Date(2027, 3, 15, 9, 0, 0, 0, LosAngeles),
+				time.Date(2028, 3, 15, 9, 0, 0, 0, LosAngeles)},
+		},
+		{
+			// Thanksgiving
+			"FREQ=YEARLY;BYMONTH=11;BYDAY=4TH",
+			[]time.Time{at(11, 26, 9, 0),
+				time.Date(2027, 11, 25, 9, 0, 0, 0, LosAngeles)},
+		},
+		{
+			// First Monday of the year
+			"FREQ=YEARLY;BYDAY=1MO",
+			[]time.Time{time.Date(2027, 1, 4, 9, 0, 0, 0, LosAngeles)},
+		},
+	}
+
+	for _, test := range tests {
+		rule, err := ParseRRule(test.rule, LosAngeles)
+		if err != nil {
+			t.Errorf("Error parsing `%s`: %v", test.rule, err)
+			continue
+		}
+
+		prev := start.Add(-time.Second)
+		for _, want := range test.next {
+			got, ok := rule.After(start, prev)
+			if !assert.True(t, ok, "%s ended early", test.rule) {
+				break
+			}
+			assert.Equal(t, want, got, "Wrong occurrence of %s after %s",
+				test.rule, prev)
+			assert.True(t, rule.Includes(start, got))
+			prev = got
+		}
+	}
+
+	// COUNT and UNTIL end the rule
+	for _, ruleStr := range []string{
+		"FREQ=WEEKLY;BYDAY=MO,WE;COUNT=3",
+		"FREQ=DAILY;INTERVAL=2;UNTIL=20261023",
+	} {
+		rule, _ := ParseRRule(ruleStr, LosAngeles)
+		_, ok := rule.After(start, at(10, 26, 9, 0))
+		assert.False(t, ok, "%s should have ended", ruleStr)
+	}
+
+	// Rules that never occur don't loop forever
+	rule, _ := ParseRRule("FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", LosAngeles)
+	_, ok := rule.After(start, start)
+	assert.False(t, ok)
+}
+
+func TestRRuleMinGap(t *testing.T) {
+	// Sunday
+	start := time.Date(2026, 10, 18, 9, 0, 0, 0, LosAngeles)
+
+	tests := []struct {
+		rule string
+		gap  time.Duration
+	}{
+		{"FREQ=DAILY", 24 * time.Hour},
+		{"FREQ=DAILY;BYMINUTE=0,5", 5 * time.Minute},
+		{"FREQ=DAILY;BYHOUR=9,17", 8 * time.Hour},
+		{"FREQ=WEEKLY;BYDAY=MO,TU", 24 * time.Hour},
+		// 11:55pm, then 12am the next day
+		{"FREQ=DAILY;BYHOUR=0,23;BYMINUTE=0,55", 5 * time.Minute},
+		// But never the next day
+		{"FREQ=WEEKLY;BYDAY=MO;BYHOUR=0,23;BYMINUTE=0,55", 55 * time.Minute},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,-1;BYHOUR=0,23;BYMINUTE=0,55",
+			5 * time.Minute},
+		// Nov 1, not counting falling back an hour
+		{"FREQ=YEARLY;BYMONTH=11;BYMONTHDAY=1;BYHOUR=0,2", 2 * time.Hour},
+	}
+	for _, test := range tests {
+		rule, err := ParseRRule(test.rule, LosAngeles)
+		if assert.NoError(t, err, test.rule) {
+			assert.Equal(t, test.gap, rule.minGap(start), test.rule)
+		}
+	}
+
+	// Reminders can't run more often than every MinPeriod
+	for _, rule := range []string{"FREQ=DAILY;BYMINUTE=0,5",
+		"FREQ=WEEKLY;BYHOUR=9;BYMINUTE=0,1,2,3,4,5,6,7,8,9",
+		"FREQ=DAILY;BYHOUR=0,23;BYMINUTE=0,55"} {
+		r := &Reminder{NextRun: start}
+		assert.Error(t, r.SetRRule(rule), rule)
+		assert.Empty(t, r.RRule)
+	}
+	r := &Reminder{NextRun: start}
+	assert.NoError(t, r.SetRRule("FREQ=DAILY;BYMINUTE=0,15"))
+
+	// Even if saved that way
+	r = &Reminder{NextRun: Now().Add(time.Hour), Start: start,
+		RRule: "FREQ=DAILY;BYMINUTE=0,5"}
+	assert.Error(t, r.Check(nil))
+}
+
+func TestReminderRRule(t *testing.T) {
+	at := func(day, hour int) time.Time {
+		return time.Date(2026, 6, day, hour, 0, 0, 0, LosAngeles)
+	}
+	prev := SetClock(NewFakeClock(at(1, 8)))
+	defer SetClock(prev)
+
+	r := &Reminder{
+		ID:      7,
+		NextRun: at(1, 9),
+	}
+
+	assert.Error(t, r.SetRRule("FREQ=SOMETIMES"))
+
+	err := r.SetRRule("FREQ=DAILY;COUNT=2")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, r.Repeats())
+	assert.Equal(t, "RRULE:FREQ=DAILY;COUNT=2", r.Repetition())
+	assert.Equal(t, at(1, 9), r.NextRun)
+
+	// Survives a round trip through bolt's JSON
+	b, err := json.Marshal(r)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var r2 Reminder
+	if !assert.NoError(t, json.Unmarshal(b, &r2)) {
+		return
+	}
+	assert.Equal(t, r.RRule, r2.RRule)
+	assert.True(t, r.Start.Equal(r2.Start))
+
+	next, ok := r2.next(r2.NextRun)
+	assert.True(t, ok)
+	assert.True(t, next.Equal(at(2, 9)), "got %v", next)
+
+	_, ok = r2.next(next)
+	assert.False(t, ok, "COUNT=2 should end after 2 runs")
+
+	// Missed runs are skipped
+	r2.NextRun = at(1, 6)
+	r2.Nominal = r2.NextRun
+	r2.Start = r2.NextRun
+	changed, err := r2.FutureizeNextRun()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, r2.NextRun.Equal(at(2, 6)), "got %v", r2.NextRun)
+
+	r2.NextRun = at(1, 8).Add(-50 * time.Hour)
+	r2.Nominal = r2.NextRun
+	r2.Start = r2.NextRun
+	_, err = r2.FutureizeNextRun()
+	assert.Equal(t, ErrNoMoreRuns, err)
+}
+
+func TestReminderRRuleDST(t *testing.T) {
+	// The day before DST ends on Sunday, Nov 1
+	start := time.Date(2026, 10, 31, 9, 0, 0, 0, LosAngeles)
+	prev := SetClock(NewFakeClock(start.Add(-time.Hour)))
+	defer SetClock(prev)
+
+	r := &Reminder{NextRun: start}
+	if !assert.NoError(t, r.SetRRule("FREQ=DAILY")) {
+		return
+	}
+
+	// Still 9am, 25 hours later
+	next, ok := r.next(r.NextRun)
+	if assert.True(t, ok) {
+		assert.Equal(t, "2026-11-01 09:00 PST", next.Format("2006-01-02 15:04 MST"))
+		assert.Equal(t, 25*time.Hour, next.Sub(start))
+	}
+	next, ok = r.next(next)
+	if assert.True(t, ok) {
+		assert.Equal(t, "2026-11-02 09:00 PST", next.Format("2006-01-02 15:04 MST"))
+	}
+}
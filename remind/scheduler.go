@@ -0,0 +1,540 @@
+package remind
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/elimisteve/do_reminder/twilhelp"
+)
+
+// DefaultWorkers is how many reminders a Scheduler sends at once,
+// unless told otherwise
+const DefaultWorkers = 8
+
+// Scheduler sends each reminder it's given when it's due. Rather than
+// a goroutine per reminder, it keeps them in a min-heap by when they're
+// due, waits for the soonest one, then hands it to a fixed pool of
+// workers.
+type Scheduler struct {
+	db      *bolt.DB
+	sender  twilhelp.Sender
+	workers int
+
+	mu    sync.Mutex
+	queue queue                 // Waiting reminders, soonest first
+	byID  map[uint64]*scheduled // Waiting reminders and those being sent
+
+	wake chan struct{} // Tells run the soonest reminder may have changed
+	jobs chan *scheduled
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// scheduled is a reminder in a Scheduler
+type scheduled struct {
+	r         *Reminder
+	index     int         // In the queue, or -1 while being sent
+	kind      sendKind    // Why it's being sent, while it is
+	occ       *Occurrence // Which run it's being escalated for, if it is
+	cancelled bool
+
+	// How many snapshots of r have been taken, and which was saved
+	// last; see save
+	snapshots uint64
+	saveMu    sync.Mutex
+	saved     uint64
+}
+
+// snapshot is a scheduled reminder as it was when taken, so it can be
+// saved without holding Scheduler.mu
+type snapshot struct {
+	e      *scheduled
+	seq    uint64
+	rBytes []byte
+	err    error
+}
+
+// sendKind is why a reminder is being sent
+type sendKind int
+
+const (
+	sendRun        sendKind = iota // It's due to run
+	sendSnoozed                    // Its recipient snoozed it
+	sendNag                        // Its last run hasn't been acknowledged
+	sendEscalation                 // Nor in time, so others are told
+)
+
+// due returns when e's reminder is next sent
+func (e *scheduled) due() time.Time {
+	at, _, _ := e.r.nextSend()
+	return at
+}
+
+// nextSend returns when r is next sent, and why: when it next runs,
+// or if sooner, when it was snoozed until, is next sent again for not
+// being acknowledged, or is escalated. ok is false if it isn't sent
+// again.
+func (r *Reminder) nextSend() (at time.Time, kind sendKind, ok bool) {
+	if !r.Completed {
+		at, kind, ok = r.NextRun, sendRun, true
+	}
+	if !r.Snoozed.IsZero() && (!ok || r.Snoozed.Before(at)) {
+		at, kind, ok = r.Snoozed, sendSnoozed, true
+	}
+	if p := r.Pending; p != nil && !p.NextNag.IsZero() &&
+		(!ok || p.NextNag.Before(at)) {
+		at, kind, ok = p.NextNag, sendNag, true
+	}
+	if p := r.Pending; p != nil && !p.EscalateAt.IsZero() &&
+		(!ok || p.EscalateAt.Before(at)) {
+		at, kind, ok = p.EscalateAt, sendEscalation, true
+	}
+	return at, kind, ok
+}
+
+// NewScheduler returns a Scheduler that saves reminders' progress to
+// db and sends them with sender, up to workers (or DefaultWorkers, if
+// < 1) at once
+func NewScheduler(db *bolt.DB, sender twilhelp.Sender, workers int) *Scheduler {
+	if workers < 1 {
+		workers = DefaultWorkers
+	}
+	return &Scheduler{
+		db:      db,
+		sender:  sender,
+		workers: workers,
+		byID:    map[uint64]*scheduled{},
+		wake:    make(chan struct{}, 1),
+		jobs:    make(chan *scheduled),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start starts sending reminders as they come due
+func (s *Scheduler) Start() {
+	s.wg.Add(1 + s.workers)
+	go s.run()
+	for i := 0; i < s.workers; i++ {
+		go s.work()
+	}
+}
+
+// Stop stops sending reminders, after waiting for those being sent
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// Schedule adds each active reminder in rems, logging those that can't
+// be scheduled
+func (s *Scheduler) Schedule(rems Reminders) {
+	for _, r := range rems.Active() {
+		if err := s.Add(r); err != nil {
+			log.Printf("Error scheduling Reminder %v: %v\n", r.ID, err)
+		}
+	}
+
+	// Finished, but snoozed or not yet acknowledged
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range rems {
+		if _, _, ok := r.nextSend(); ok && r.Completed && !r.Cancelled {
+			s.push(r)
+		}
+	}
+}
+
+// Add checks r (see Reminder.Check) and schedules it
+func (s *Scheduler) Add(r *Reminder) error {
+	if err := r.Check(s.db); err != nil {
+		return err
+	}
+	if r.Cancelled || r.Completed {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.byID[r.ID]; ok {
+		return fmt.Errorf("Reminder %v is already scheduled", r.ID)
+	}
+	s.push(r)
+
+	log.Printf("Valid Reminder %v scheduled: %s\n", r.ID, r)
+
+	return nil
+}
+
+// CancelFor cancels and unschedules the reminders with the given IDs
+// on behalf of owner, returning nil for each that was cancelled, or why
+// it wasn't: ErrReminderNotFound, ErrNotOwner, ErrAlreadyStopped, or
+// some other error
+func (s *Scheduler) CancelFor(owner string, ids []uint64) []error {
+	errs := make([]error, len(ids))
+	for i, id := range ids {
+		errs[i] = s.cancelFor(owner, id)
+	}
+	return errs
+}
+
+// cancelFor cancels the reminder with ID id if owner is its recipient
+func (s *Scheduler) cancelFor(owner string, id uint64) error {
+	sn, scheduled, err := s.unschedule(owner, id)
+	if err != nil {
+		return err
+	}
+
+	if !scheduled {
+		r, err := GetOwnReminder(s.db, owner, id)
+		if err != nil {
+			return err
+		}
+		if r.Cancelled || r.Completed {
+			return ErrAlreadyStopped
+		}
+		// Saved, but not scheduled, e.g. if it couldn't be
+		return r.Cancel(s.db)
+	}
+
+	if err := s.save(sn); err != nil {
+		return fmt.Errorf("Cancelled currently-running Reminder, but"+
+			" failed to save: %v", err)
+	}
+	return nil
+}
+
+// unschedule cancels and unschedules the reminder with ID id if it's
+// scheduled and owner is its recipient, returning it as of then
+func (s *Scheduler) unschedule(owner string, id uint64) (sn *snapshot, scheduled bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.byID[id]
+	if !ok {
+		return nil, false, nil
+	}
+	if e.r.Recipient != owner {
+		return nil, true, ErrNotOwner
+	}
+
+	s.remove(e)
+	e.r.Cancelled = true
+	return s.snapshot(e), true, nil
+}
+
+// Reschedule moves the next run of the reminder with the given ID to
+// nominal (plus or minus its PlusMinus) on behalf of owner, who must be
+// its recipient
+func (s *Scheduler) Reschedule(owner string, id uint64, nominal time.Time) error {
+	sn, err := s.reschedule(owner, id, nominal)
+	if err != nil {
+		return err
+	}
+	return s.save(sn)
+}
+
+// reschedule is Reschedule, short of saving the reminder
+func (s *Scheduler) reschedule(owner string, id uint64, nominal time.Time) (*snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.byID[id]
+	if !ok {
+		return nil, ErrReminderNotFound
+	}
+	if e.r.Recipient != owner {
+		return nil, ErrNotOwner
+	}
+	if e.index < 0 {
+		return nil, fmt.Errorf("Reminder %v is being sent right now", id)
+	}
+
+	e.r.setNext(nominal)
+	heap.Fix(&s.queue, e.index)
+	s.notify()
+
+	return s.snapshot(e), nil
+}
+
+// Len returns the number of scheduled reminders
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.byID)
+}
+
+// push schedules r. s.mu must be held.
+func (s *Scheduler) push(r *Reminder) {
+	e := &scheduled{r: r}
+	s.byID[r.ID] = e
+	heap.Push(&s.queue, e)
+	s.notify()
+}
+
+// snapshot returns e's reminder as it is now, to be saved once s.mu
+// is released. s.mu must be held.
+func (s *Scheduler) snapshot(e *scheduled) *snapshot {
+	e.snapshots++
+	rBytes, err := json.Marshal(e.r)
+	return &snapshot{e: e, seq: e.snapshots, rBytes: rBytes, err: err}
+}
+
+// save saves sn, unless a later snapshot of its reminder was saved
+// first. s.mu mustn't be held, so nothing waits on the disk.
+func (s *Scheduler) save(sn *snapshot) error {
+	if sn.err != nil {
+		return sn.err
+	}
+
+	e := sn.e
+	e.saveMu.Lock()
+	defer e.saveMu.Unlock()
+
+	if sn.seq < e.saved {
+		return nil
+	}
+	if err := putReminder(s.db, e.r.ID, sn.rBytes); err != nil {
+		return err
+	}
+	e.saved = sn.seq
+	return nil
+}
+
+// remove unschedules e. s.mu must be held.
+func (s *Scheduler) remove(e *scheduled) {
+	e.cancelled = true
+	if e.index >= 0 {
+		heap.Remove(&s.queue, e.index)
+	}
+	delete(s.byID, e.r.ID)
+	s.notify()
+}
+
+// notify wakes run, if it isn't already awake
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run hands each reminder to a worker when it's due
+func (s *Scheduler) run() {
+	defer s.wg.Done()
+
+	var timer Timer
+
+	for {
+		due, wait := s.popDue()
+
+		for _, e := range due {
+			select {
+			case s.jobs <- e:
+			case <-s.stop:
+				return
+			}
+		}
+		if len(due) > 0 {
+			// Sending may have taken a while
+			continue
+		}
+
+		if timer == nil {
+			timer = clock.NewTimer(wait)
+			defer timer.Stop()
+		} else {
+			if !timer.Stop() {
+				select {
+				case <-timer.C():
+				default:
+				}
+			}
+			timer.Reset(wait)
+		}
+
+		select {
+		case <-timer.C():
+		case <-s.wake:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// popDue removes and returns the reminders due now, or if there are
+// none, returns how long until the next one is
+func (s *Scheduler) popDue() (due []*scheduled, wait time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := Now()
+	for s.queue.Len() > 0 && !s.queue[0].due().After(now) {
+		e := heap.Pop(&s.queue).(*scheduled)
+		_, e.kind, _ = e.r.nextSend()
+		e.occ = nil
+		if e.kind == sendEscalation {
+			e.occ = e.r.Pending
+		}
+		due = append(due, e)
+	}
+
+	if len(due) > 0 || s.queue.Len() == 0 {
+		// Until something's added
+		return due, time.Hour
+	}
+	return nil, s.queue[0].due().Sub(now)
+}
+
+func (s *Scheduler) work() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case e := <-s.jobs:
+			s.send(e)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// send sends e's reminder, then schedules its next run, if any
+func (s *Scheduler) send(e *scheduled) {
+	if e.kind == sendEscalation {
+		s.escalate(e)
+		return
+	}
+
+	r := e.r
+
+	log.Printf("Texting `%s` to remind him/her to `%s` (%s +/- within %s)\n",
+		r.Recipient, r.Description, r.Repetition(), r.PlusMinus)
+
+	// Nothing else changes the fields Send reads while r is being
+	// sent, so this needn't hold s.mu
+	msgID, sendErr := r.Send(context.Background(), s.sender)
+	if sendErr != nil {
+		log.Printf("Error sending SMS `%v` to `%v`: %v\n", r.Description,
+			r.Recipient, sendErr)
+	} else {
+		log.Printf("Reminder %v sent as message %v\n", r.ID, msgID)
+		if err := logSend(s.db, r, msgID); err != nil {
+			log.Printf("Error logging Reminder %v as sent: %v\n", r.ID, err)
+		}
+	}
+
+	sn, more := s.sent(e, sendErr)
+	if sn != nil {
+		if err := s.save(sn); err != nil {
+			log.Printf("Error saving Reminder %v: %v\n", r.ID, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Cancelled meanwhile, and saved as such
+	if e.cancelled {
+		return
+	}
+	if !more {
+		delete(s.byID, r.ID)
+		return
+	}
+	s.requeue(e)
+}
+
+// sent records that e's reminder was just sent (unsuccessfully if
+// sendErr isn't nil), returning it as of then to be saved, or nil if
+// it was cancelled meanwhile. more is false if it's never sent again.
+func (s *Scheduler) sent(e *scheduled, sendErr error) (sn *snapshot, more bool) {
+	r := e.r
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e.cancelled {
+		log.Printf("Reminder %v cancelled while being sent\n", r.ID)
+		return nil, false
+	}
+
+	switch e.kind {
+	case sendSnoozed:
+		// Sending it again doesn't change its schedule
+		r.Snoozed = time.Time{}
+
+	case sendNag:
+		// Unless it was acknowledged while being sent
+		if r.Pending != nil {
+			r.nagged()
+		}
+
+	default:
+		// Snoozed until this run anyway
+		if !r.Snoozed.IsZero() && !r.Snoozed.After(Now()) {
+			r.Snoozed = time.Time{}
+		}
+		if r.MustAck() && sendErr == nil {
+			r.startOccurrence()
+		}
+
+		more, err := r.advance(sendErr)
+		if err != nil {
+			log.Printf("Error running Reminder %v: %v\n", r.ID, err)
+		}
+		if !more && !r.Completed {
+			return s.snapshot(e), false
+		}
+	}
+
+	return s.snapshot(e), true
+}
+
+// requeue schedules e again if its reminder is sent again. s.mu must
+// be held.
+func (s *Scheduler) requeue(e *scheduled) {
+	if _, _, ok := e.r.nextSend(); !ok {
+		delete(s.byID, e.r.ID)
+		return
+	}
+	heap.Push(&s.queue, e)
+	s.notify()
+}
+
+// queue is a min-heap of reminders by when they're due (see
+// container/heap)
+type queue []*scheduled
+
+func (q queue) Len() int { return len(q) }
+
+func (q queue) Less(i, j int) bool {
+	return q[i].due().Before(q[j].due())
+}
+
+func (q queue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *queue) Push(x interface{}) {
+	e := x.(*scheduled)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+
+func (q *queue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	e.index = -1
+	*q = old[:len(old)-1]
+	return e
+}
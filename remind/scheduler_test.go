@@ -0,0 +1,346 @@
+package remind
+
+import (
+	"container/heap"
+	"testing"
+	"time"
+
+	"github.com/elimisteve/do_reminder/twilhelp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedulerQueue(t *testing.T) {
+	db := openTestDB(t)
+	s := NewScheduler(db, twilhelp.NewFakeSender(), 0)
+	now := Now()
+
+	var rems Reminders
+	for _, hours := range []int{5, 1, 3, 2, 4} {
+		r := &Reminder{
+			Recipient:   "+15555550100",
+			Description: "Test",
+			NextRun:     now.Add(time.Duration(hours) * time.Hour),
+		}
+		if !assert.NoError(t, r.Save(db)) {
+			return
+		}
+		rems = append(rems, r)
+	}
+	cancelled := &Reminder{NextRun: now.Add(time.Hour), Cancelled: true}
+	s.Schedule(append(rems, cancelled))
+
+	assert.Equal(t, 5, s.Len())
+	assert.Equal(t, rems[1], s.queue[0].r)
+	assert.Error(t, s.Add(rems[0]), "Already scheduled")
+
+	_, wait := s.popDue()
+	assert.InDelta(t, float64(time.Hour), float64(wait), float64(time.Second))
+
+	// Cancel
+	owner := rems[1].Recipient
+	assert.Equal(t, []error{nil}, s.CancelFor(owner, []uint64{rems[1].ID}))
+	assert.Equal(t, 4, s.Len())
+	assert.Equal(t, rems[3], s.queue[0].r)
+	assert.True(t, rems[1].Cancelled)
+	assert.Equal(t, []error{ErrAlreadyStopped, ErrReminderNotFound},
+		s.CancelFor(owner, []uint64{rems[1].ID, 999}))
+
+	saved, err := GetAllReminders(db)
+	if assert.NoError(t, err) {
+		assert.Len(t, saved.Active(), 4)
+	}
+
+	// Reschedule
+	assert.Equal(t, ErrNotOwner, s.Reschedule("+15555550199", rems[0].ID,
+		now.Add(30*time.Minute)))
+	assert.NoError(t, s.Reschedule(owner, rems[0].ID, now.Add(30*time.Minute)))
+	assert.Equal(t, rems[0], s.queue[0].r)
+	assert.Equal(t, ErrReminderNotFound, s.Reschedule(owner, 999, now))
+
+	// Due reminders are popped in order
+	rems[2].NextRun = now.Add(-time.Minute)
+	rems[4].NextRun = now.Add(-2 * time.Minute)
+	s.queue.fix(rems[2], rems[4])
+	due, _ := s.popDue()
+	if assert.Len(t, due, 2) {
+		assert.Equal(t, rems[4], due[0].r)
+		assert.Equal(t, rems[2], due[1].r)
+		assert.Equal(t, -1, due[0].index)
+	}
+	assert.Equal(t, rems[0], s.queue[0].r)
+}
+
+// fix restores q's heap order after the given reminders' NextRuns
+// change
+func (q *queue) fix(rems ...*Reminder) {
+	for _, r := range rems {
+		for _, e := range *q {
+			if e.r == r {
+				heap.Fix(q, e.index)
+				break
+			}
+		}
+	}
+}
+
+// newTestScheduler returns a Scheduler whose reminders are "sent" to
+// the returned FakeSender, and which uses a FakeClock set to a Monday
+// morning
+func newTestScheduler(t *testing.T) (*Scheduler, *FakeClock, *twilhelp.FakeSender) {
+	clk := NewFakeClock(time.Date(2026, 6, 1, 8, 0, 0, 0, LosAngeles))
+	prev := SetClock(clk)
+	t.Cleanup(func() { SetClock(prev) })
+
+	sent := twilhelp.NewFakeSender()
+	s := NewScheduler(openTestDB(t), sent, 2)
+
+	return s, clk, sent
+}
+
+// addTestReminder saves and schedules r
+func addTestReminder(t *testing.T, s *Scheduler, r *Reminder) {
+	r.Recipient = "+15555550100"
+	r.Description = "Test"
+	if err := r.Save(s.db); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(r); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// sendDue sends every due reminder, like run and work do
+func (s *Scheduler) sendDue() int {
+	due, _ := s.popDue()
+	for _, e := range due {
+		s.send(e)
+	}
+	return len(due)
+}
+
+func TestSchedulerOneShot(t *testing.T) {
+	s, clk, sent := newTestScheduler(t)
+	r := &Reminder{NextRun: Now().Add(time.Hour)}
+	addTestReminder(t, s, r)
+
+	_, wait := s.popDue()
+	assert.Equal(t, time.Hour, wait)
+
+	clk.Advance(59 * time.Minute)
+	assert.Equal(t, 0, s.sendDue())
+
+	clk.Advance(time.Minute)
+	assert.Equal(t, 1, s.sendDue())
+	assert.Len(t, sent.Sent(), 1)
+	assert.True(t, r.Completed)
+	assert.Equal(t, 0, s.Len())
+
+	saved, err := GetAllReminders(s.db)
+	if assert.NoError(t, err) && assert.Len(t, saved, 1) {
+		assert.True(t, saved[0].Completed)
+	}
+}
+
+func TestSchedulerDaily(t *testing.T) {
+	s, clk, sent := newTestScheduler(t)
+	start := time.Date(2026, 6, 1, 18, 0, 0, 0, LosAngeles)
+	r := &Reminder{NextRun: start, Period: 24 * time.Hour}
+	addTestReminder(t, s, r)
+
+	clk.Advance(10 * time.Hour)
+	for day := 0; day < 3; day++ {
+		assert.Equal(t, 1, s.sendDue())
+		assert.Equal(t, start.AddDate(0, 0, day+1), r.NextRun)
+		assert.Equal(t, 0, s.sendDue(), "Not due again until tomorrow")
+		clk.Advance(24 * time.Hour)
+	}
+	assert.Len(t, sent.Sent(), 3)
+	assert.Equal(t, 3, r.Runs)
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestSchedulerJittered(t *testing.T) {
+	s, clk, sent := newTestScheduler(t)
+	start := time.Date(2026, 6, 1, 18, 0, 0, 0, LosAngeles)
+	r := &Reminder{NextRun: start, Period: 24 * time.Hour,
+		PlusMinus: 30 * time.Minute}
+	addTestReminder(t, s, r)
+
+	for day := 0; day < 20; day++ {
+		nominal := start.AddDate(0, 0, day)
+		assert.Equal(t, nominal, r.Nominal)
+		assert.InDelta(t, 0, r.NextRun.Sub(nominal), float64(r.PlusMinus))
+
+		_, wait := s.popDue()
+		clk.Advance(wait)
+		assert.Equal(t, 1, s.sendDue())
+	}
+	assert.Len(t, sent.Sent(), 20)
+}
+
+func TestSchedulerCancelled(t *testing.T) {
+	s, clk, sent := newTestScheduler(t)
+	r1 := &Reminder{NextRun: Now().Add(time.Hour), Period: time.Hour}
+	r2 := &Reminder{NextRun: Now().Add(time.Hour), Period: time.Hour}
+	addTestReminder(t, s, r1)
+	addTestReminder(t, s, r2)
+
+	assert.Equal(t, []error{nil}, s.CancelFor(r1.Recipient, []uint64{r1.ID}))
+	clk.Advance(time.Hour)
+
+	// Cancelled while being sent
+	due, _ := s.popDue()
+	if assert.Len(t, due, 1) {
+		assert.Equal(t, r2, due[0].r)
+		assert.Equal(t, []error{nil},
+			s.CancelFor(r2.Recipient, []uint64{r2.ID}))
+		s.send(due[0])
+	}
+	assert.Len(t, sent.Sent(), 1)
+	assert.Equal(t, 0, s.Len())
+	assert.Equal(t, 0, r2.Runs, "Cancelled reminders aren't advanced")
+
+	clk.Advance(time.Hour)
+	assert.Equal(t, 0, s.sendDue())
+
+	saved, err := GetAllReminders(s.db)
+	if assert.NoError(t, err) {
+		assert.Len(t, saved.Active(), 0)
+	}
+}
+
+func TestSchedulerRun(t *testing.T) {
+	s, clk, sent := newTestScheduler(t)
+	r1 := &Reminder{NextRun: Now().Add(time.Minute)}
+	r2 := &Reminder{NextRun: Now().Add(time.Hour), Period: time.Hour}
+	addTestReminder(t, s, r1)
+	addTestReminder(t, s, r2)
+
+	s.Start()
+
+	// received waits for the nth text, then returns it
+	received := func(n int) string {
+		msgs, ok := sent.WaitFor(n, 5*time.Second)
+		if !ok {
+			t.Fatal("Timed out waiting for a reminder to be sent")
+		}
+		return msgs[n-1].Body
+	}
+
+	// Wait for the scheduler to wait for r1
+	clk.BlockUntil(1)
+	assert.Len(t, sent.Sent(), 0)
+	clk.Advance(time.Minute)
+	assert.Equal(t, r1.Message(), received(1))
+
+	clk.BlockUntil(1)
+	clk.Advance(59 * time.Minute)
+	assert.Equal(t, r2.Message(), received(2))
+
+	// Reminders added while running are picked up. The scheduler may
+	// still be waiting for r2's next run when r3 is added, so this
+	// can't wait for it to wait for r3.
+	r3 := &Reminder{NextRun: Now().Add(time.Minute)}
+	addTestReminder(t, s, r3)
+	for i := 0; i < 120; i++ {
+		clk.Advance(time.Second)
+		if _, ok := sent.WaitFor(3, time.Millisecond); ok {
+			break
+		}
+	}
+	assert.Equal(t, r3.Message(), received(3))
+
+	// Once it's done sending
+	s.Stop()
+	assert.Len(t, sent.Sent(), 3)
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestSchedulerCancelFor(t *testing.T) {
+	s, _, _ := newTestScheduler(t)
+	r1 := &Reminder{NextRun: Now().Add(time.Hour)}
+	r2 := &Reminder{NextRun: Now().Add(time.Hour)}
+	addTestReminder(t, s, r1)
+	addTestReminder(t, s, r2)
+
+	errs := s.CancelFor("+15555550199", []uint64{r1.ID, 99})
+	assert.Equal(t, []error{ErrNotOwner, ErrReminderNotFound}, errs)
+	assert.Equal(t, 2, s.Len())
+
+	errs = s.CancelFor(r1.Recipient, []uint64{r1.ID, r1.ID})
+	assert.Equal(t, []error{nil, ErrAlreadyStopped}, errs)
+	assert.True(t, r1.Cancelled)
+	assert.Equal(t, 1, s.Len())
+
+	// Saved but not scheduled
+	r3 := &Reminder{Recipient: r1.Recipient, NextRun: Now().Add(time.Hour)}
+	if assert.NoError(t, r3.Save(s.db)) {
+		assert.Equal(t, []error{nil}, s.CancelFor(r1.Recipient, []uint64{r3.ID}))
+		saved, err := GetReminder(s.db, r3.ID)
+		if assert.NoError(t, err) {
+			assert.True(t, saved.Cancelled)
+		}
+	}
+}
+
+func TestSchedulerSavesUnlocked(t *testing.T) {
+	s, _, _ := newTestScheduler(t)
+	r := &Reminder{NextRun: Now().Add(time.Hour)}
+	addTestReminder(t, s, r)
+	at := Now().Add(2 * time.Hour)
+
+	// Hold up writes to the DB
+	tx, err := s.db.Begin(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rescheduled := make(chan error)
+	go func() {
+		rescheduled <- s.Reschedule(r.Recipient, r.ID, at)
+	}()
+
+	// Meanwhile, the queue is free
+	wait := time.After(5 * time.Second)
+	for moved := false; !moved; {
+		locked := make(chan bool, 1)
+		go func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			locked <- r.NextRun.Equal(at)
+		}()
+		select {
+		case moved = <-locked:
+		case <-wait:
+			t.Fatal("Scheduler locked while saving")
+		}
+	}
+
+	tx.Rollback()
+	assert.NoError(t, <-rescheduled)
+	saved, err := GetReminder(s.db, r.ID)
+	if assert.NoError(t, err) {
+		assert.True(t, saved.NextRun.Equal(at))
+	}
+}
+
+func TestSchedulerSavesLatest(t *testing.T) {
+	s, _, _ := newTestScheduler(t)
+	r := &Reminder{NextRun: Now().Add(time.Hour)}
+	addTestReminder(t, s, r)
+	e := s.byID[r.ID]
+
+	// Saved out of order
+	s.mu.Lock()
+	r.Description = "First"
+	first := s.snapshot(e)
+	r.Description = "Second"
+	second := s.snapshot(e)
+	s.mu.Unlock()
+	assert.NoError(t, s.save(second))
+	assert.NoError(t, s.save(first))
+
+	saved, err := GetReminder(s.db, r.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Second", saved.Description)
+	}
+}
@@ -0,0 +1,106 @@
+package remind
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/elimisteve/do_reminder/twilhelp"
+)
+
+// sendLogBucket holds a bucket per recipient of the last MaxSendLog
+// reminders sent to them, oldest first
+var sendLogBucket = []byte("send_log")
+
+// MaxSendLog is how many sent reminders are kept per recipient; older
+// ones are dropped as new ones are logged
+const MaxSendLog = 10
+
+var ErrNothingSent = errors.New("No reminders have been sent to you yet")
+
+// SentReminder records a reminder being sent
+type SentReminder struct {
+	ReminderID uint64
+	Number     uint64
+	Nominal    time.Time // The run it was sent for
+	At         time.Time
+	MessageID  twilhelp.MessageID `json:",omitempty"`
+}
+
+// logSend records that r was just sent as msgID, dropping the
+// oldest of its recipient's sends past MaxSendLog
+func logSend(db *bolt.DB, r *Reminder, msgID twilhelp.MessageID) error {
+	sent := &SentReminder{
+		ReminderID: r.ID,
+		Number:     r.Num(),
+		Nominal:    r.Nominal,
+		At:         Now(),
+		MessageID:  msgID,
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		sendLog, err := tx.CreateBucketIfNotExists(sendLogBucket)
+		if err != nil {
+			return err
+		}
+		theirs, err := sendLog.CreateBucketIfNotExists([]byte(r.Recipient))
+		if err != nil {
+			return err
+		}
+
+		seq, _ := theirs.NextSequence()
+		sentBytes, err := json.Marshal(sent)
+		if err != nil {
+			return err
+		}
+		if err := theirs.Put(itob(seq), sentBytes); err != nil {
+			return err
+		}
+
+		// Keys are ascending sequence numbers, so everything before
+		// the newest MaxSendLog is oldest first
+		var old [][]byte
+		c := theirs.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			old = append(old, k)
+		}
+		if len(old) <= MaxSendLog {
+			return nil
+		}
+		for _, k := range old[:len(old)-MaxSendLog] {
+			if err := theirs.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// LastSent returns the reminder most recently sent to recipient, or
+// ErrNothingSent
+func LastSent(db *bolt.DB, recipient string) (*SentReminder, error) {
+	var sent SentReminder
+
+	err := db.View(func(tx *bolt.Tx) error {
+		sendLog := tx.Bucket(sendLogBucket)
+		if sendLog == nil {
+			return ErrNothingSent
+		}
+		theirs := sendLog.Bucket([]byte(recipient))
+		if theirs == nil {
+			return ErrNothingSent
+		}
+
+		_, v := theirs.Cursor().Last()
+		if v == nil {
+			return ErrNothingSent
+		}
+		return json.Unmarshal(v, &sent)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &sent, nil
+}
@@ -0,0 +1,49 @@
+package remind
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogSendTrims(t *testing.T) {
+	clk := NewFakeClock(time.Date(2026, 6, 1, 8, 0, 0, 0, LosAngeles))
+	prev := SetClock(clk)
+	defer SetClock(prev)
+
+	db := openTestDB(t)
+	r := &Reminder{ID: 1, Recipient: "+15555550100"}
+	other := &Reminder{ID: 2, Recipient: "+15555550101"}
+
+	assert.NoError(t, logSend(db, other, ""))
+	for i := 0; i < MaxSendLog+5; i++ {
+		r.Runs = i
+		assert.NoError(t, logSend(db, r, ""))
+		clk.Advance(time.Hour)
+	}
+
+	var nums []uint64
+	db.View(func(tx *bolt.Tx) error {
+		theirs := tx.Bucket(sendLogBucket).Bucket([]byte(r.Recipient))
+		return theirs.ForEach(func(k, v []byte) error {
+			nums = append(nums, binary.BigEndian.Uint64(k))
+			return nil
+		})
+	})
+	if assert.Len(t, nums, MaxSendLog) {
+		assert.Equal(t, uint64(6), nums[0], "Oldest dropped first")
+	}
+
+	last, err := LastSent(db, r.Recipient)
+	if assert.NoError(t, err) {
+		assert.Equal(t, r.Num(), last.Number)
+	}
+
+	last, err = LastSent(db, other.Recipient)
+	if assert.NoError(t, err) {
+		assert.Equal(t, other.ID, last.ReminderID, "Others' sends kept")
+	}
+}
@@ -0,0 +1,85 @@
+package remind
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultSnooze is how long a reminder is snoozed for if the
+	// recipient doesn't say
+	DefaultSnooze = 10 * time.Minute
+
+	// MaxSnooze is the longest a reminder may be snoozed for
+	MaxSnooze = 24 * time.Hour
+)
+
+// Snooze sends the reminder with ID id to owner again d from now,
+// once, without changing when it next runs otherwise. Reminders that
+// have already finished may be snoozed too.
+func (s *Scheduler) Snooze(owner string, id uint64, d time.Duration) (time.Time, error) {
+	if d <= 0 || d > MaxSnooze {
+		return time.Time{}, fmt.Errorf("Reminders can be snoozed for up to"+
+			" %v, not %v", MaxSnooze, d)
+	}
+	at := Now().Add(d)
+
+	sn, scheduled, err := s.snoozeScheduled(owner, id, at)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if scheduled {
+		return at, s.save(sn)
+	}
+
+	r, err := GetOwnReminder(s.db, owner, id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if r.Cancelled {
+		return time.Time{}, ErrAlreadyStopped
+	}
+	if !r.Completed {
+		return time.Time{}, fmt.Errorf("Reminder %v isn't scheduled", id)
+	}
+
+	r.Snoozed = at
+	if err := r.Update(s.db); err != nil {
+		return time.Time{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Unless snoozed again meanwhile
+	if _, ok := s.byID[id]; !ok {
+		s.push(r)
+	}
+
+	return at, nil
+}
+
+// snoozeScheduled is Snooze for the reminder with ID id if it's
+// scheduled, short of saving it
+func (s *Scheduler) snoozeScheduled(owner string, id uint64, at time.Time) (sn *snapshot, scheduled bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.byID[id]
+	if !ok {
+		return nil, false, nil
+	}
+	if e.r.Recipient != owner {
+		return nil, true, ErrNotOwner
+	}
+	if e.index < 0 {
+		return nil, true, fmt.Errorf("Reminder %v is being sent right now", id)
+	}
+
+	e.r.Snoozed = at
+	heap.Fix(&s.queue, e.index)
+	s.notify()
+
+	return s.snapshot(e), true, nil
+}
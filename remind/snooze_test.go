@@ -0,0 +1,100 @@
+package remind
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedulerSnooze(t *testing.T) {
+	s, clk, sent := newTestScheduler(t)
+	start := time.Date(2026, 6, 1, 18, 0, 0, 0, LosAngeles)
+	r := &Reminder{NextRun: start, Period: 24 * time.Hour}
+	addTestReminder(t, s, r)
+
+	_, err := LastSent(s.db, r.Recipient)
+	assert.Equal(t, ErrNothingSent, err)
+
+	clk.Advance(10 * time.Hour)
+	assert.Equal(t, 1, s.sendDue())
+
+	last, err := LastSent(s.db, r.Recipient)
+	if assert.NoError(t, err) {
+		assert.Equal(t, r.ID, last.ReminderID)
+		assert.True(t, start.Equal(last.Nominal))
+	}
+
+	_, err = s.Snooze("+15555550199", r.ID, 10*time.Minute)
+	assert.Equal(t, ErrNotOwner, err)
+	_, err = s.Snooze(r.Recipient, r.ID, 25*time.Hour)
+	assert.Error(t, err)
+
+	at, err := s.Snooze(r.Recipient, r.ID, 10*time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, start.Add(10*time.Minute), at)
+
+	_, wait := s.popDue()
+	assert.Equal(t, 10*time.Minute, wait)
+	clk.Advance(wait)
+	assert.Equal(t, 1, s.sendDue())
+
+	// Sent again, without changing its schedule
+	msgs := sent.Sent()
+	if assert.Len(t, msgs, 2) {
+		assert.Equal(t, msgs[0].Body, msgs[1].Body)
+	}
+	assert.True(t, r.Snoozed.IsZero())
+	assert.Equal(t, 1, r.Runs)
+	assert.Equal(t, start.AddDate(0, 0, 1), r.Nominal)
+	_, wait = s.popDue()
+	assert.Equal(t, 24*time.Hour-10*time.Minute, wait)
+
+	// Snoozed past its next run, which goes out as usual
+	_, err = s.Snooze(r.Recipient, r.ID, 24*time.Hour)
+	assert.NoError(t, err)
+	clk.Advance(wait)
+	assert.Equal(t, 1, s.sendDue())
+	assert.Equal(t, 2, r.Runs)
+	_, wait = s.popDue()
+	assert.Equal(t, 10*time.Minute, wait, "Still snoozed")
+}
+
+func TestSnoozeFinished(t *testing.T) {
+	s, clk, sent := newTestScheduler(t)
+	r := &Reminder{NextRun: Now().Add(time.Hour)}
+	addTestReminder(t, s, r)
+
+	clk.Advance(time.Hour)
+	assert.Equal(t, 1, s.sendDue())
+	assert.True(t, r.Completed)
+	assert.Equal(t, 0, s.Len())
+
+	_, err := s.Snooze(r.Recipient, r.ID, 5*time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, s.Len())
+
+	// Still snoozed after a restart
+	s = NewScheduler(s.db, sent, 1)
+	rems, err := GetAllReminders(s.db)
+	if !assert.NoError(t, err) {
+		return
+	}
+	s.Schedule(rems)
+	assert.Equal(t, 1, s.Len())
+
+	clk.Advance(5 * time.Minute)
+	assert.Equal(t, 1, s.sendDue())
+	assert.Len(t, sent.Sent(), 2)
+	assert.Equal(t, 0, s.Len())
+
+	saved, err := GetReminder(s.db, r.ID)
+	if assert.NoError(t, err) {
+		assert.True(t, saved.Completed)
+		assert.True(t, saved.Snoozed.IsZero())
+	}
+
+	assert.NoError(t, saved.Cancel(s.db))
+	_, err = s.Snooze(r.Recipient, r.ID, 5*time.Minute)
+	assert.Equal(t, ErrAlreadyStopped, err)
+}
@@ -3,13 +3,83 @@
 
 package remind
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
 
 var (
-	// TODO(elimisteve): Make timezone user-specific
+	// LosAngeles is the location of users who haven't set or implied
+	// another one
 	LosAngeles, _ = time.LoadLocation("America/Los_Angeles")
+
+	locations   = map[string]*time.Location{LosAngeles.String(): LosAngeles}
+	locationsMu sync.Mutex
 )
 
+// Now returns the Clock's current time in LosAngeles
 func Now() time.Time {
-	return time.Now().In(LosAngeles).Round(time.Second)
+	return clock.Now().In(LosAngeles).Round(time.Second)
+}
+
+// LoadLocation is like time.LoadLocation, but caches each location so
+// the tz database is only read once per timezone
+func LoadLocation(name string) (*time.Location, error) {
+	locationsMu.Lock()
+	defer locationsMu.Unlock()
+
+	if loc, ok := locations[name]; ok {
+		return loc, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	// "Local" depends on the server, not the user, and "" is UTC
+	if loc == time.Local || strings.EqualFold(name, "local") || name == "" {
+		return nil, fmt.Errorf("Unknown timezone %q", name)
+	}
+
+	locations[name] = loc
+	return loc, nil
+}
+
+// WallClock is like time.Date, but defines what happens to local times
+// that daylight saving time skips or repeats, where time.Date's choice
+// varies by location. Like RFC 5545, a skipped time (e.g. 02:30 on the
+// day clocks spring forward) is moved forward by the length of the gap
+// (to 03:30), and a repeated time (01:30 on the day they fall back) is
+// its first occurrence.
+func WallClock(year int, month time.Month, day, hour, min, sec int, loc *time.Location) time.Time {
+	// The wall clock time as if it were UTC, normalized
+	wall := time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+	year, month, day = wall.Date()
+	hour, min, sec = wall.Clock()
+
+	// The UTC offsets in effect a day before and after, at least one of
+	// which is in effect at the wall clock time (unless it's skipped)
+	_, offsetBefore := wall.Add(-24 * time.Hour).In(loc).Zone()
+	_, offsetAfter := wall.Add(24 * time.Hour).In(loc).Zone()
+
+	var first time.Time
+	for _, offset := range []int{offsetBefore, offsetAfter} {
+		t := wall.Add(-time.Duration(offset) * time.Second).In(loc)
+		y, mo, d := t.Date()
+		h, mi, s := t.Clock()
+		if y != year || mo != month || d != day || h != hour || mi != min || s != sec {
+			continue
+		}
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+	}
+	if !first.IsZero() {
+		return first
+	}
+
+	// Skipped; read it with the offset from before the gap
+	return wall.Add(-time.Duration(offsetBefore) * time.Second).In(loc)
 }
@@ -0,0 +1,40 @@
+package remind
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWallClock(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	sydney, _ := time.LoadLocation("Australia/Sydney")
+
+	tests := []struct {
+		got  time.Time
+		want string
+	}{
+		// Ordinary times are the same as time.Date's
+		{WallClock(2026, 7, 4, 18, 0, 0, LosAngeles), "2026-07-04 18:00 PDT"},
+		{WallClock(2026, 12, 32, 9, 0, 0, LosAngeles), "2027-01-01 09:00 PST"},
+
+		// Skipped times move forward by the gap
+		{WallClock(2026, 3, 8, 2, 30, 0, LosAngeles), "2026-03-08 03:30 PDT"},
+		{WallClock(2026, 3, 8, 2, 0, 0, LosAngeles), "2026-03-08 03:00 PDT"},
+		{WallClock(2026, 3, 29, 2, 30, 0, berlin), "2026-03-29 03:30 CEST"},
+		{WallClock(2026, 10, 4, 2, 15, 0, sydney), "2026-10-04 03:15 AEDT"},
+
+		// Repeated times are the first of the two
+		{WallClock(2026, 11, 1, 1, 30, 0, LosAngeles), "2026-11-01 01:30 PDT"},
+		{WallClock(2026, 10, 25, 2, 30, 0, berlin), "2026-10-25 02:30 CEST"},
+		{WallClock(2026, 4, 5, 2, 30, 0, sydney), "2026-04-05 02:30 AEDT"},
+
+		// Just after the repeated hour
+		{WallClock(2026, 11, 1, 2, 0, 0, LosAngeles), "2026-11-01 02:00 PST"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, test.got.Format("2006-01-02 15:04 MST"))
+	}
+}
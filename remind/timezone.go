@@ -0,0 +1,108 @@
+package remind
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// zonesByCallingCode are the timezones most people with phone numbers
+// from each country calling code live in. Countries spanning several
+// timezones (e.g. +1, +7, +55) aren't guessed, except where most of
+// the population shares one.
+var zonesByCallingCode = map[string]string{
+	"20":  "Africa/Cairo",
+	"27":  "Africa/Johannesburg",
+	"30":  "Europe/Athens",
+	"31":  "Europe/Amsterdam",
+	"32":  "Europe/Brussels",
+	"33":  "Europe/Paris",
+	"34":  "Europe/Madrid",
+	"36":  "Europe/Budapest",
+	"39":  "Europe/Rome",
+	"40":  "Europe/Bucharest",
+	"41":  "Europe/Zurich",
+	"43":  "Europe/Vienna",
+	"44":  "Europe/London",
+	"45":  "Europe/Copenhagen",
+	"46":  "Europe/Stockholm",
+	"47":  "Europe/Oslo",
+	"48":  "Europe/Warsaw",
+	"49":  "Europe/Berlin",
+	"51":  "America/Lima",
+	"52":  "America/Mexico_City",
+	"54":  "America/Argentina/Buenos_Aires",
+	"55":  "America/Sao_Paulo",
+	"56":  "America/Santiago",
+	"57":  "America/Bogota",
+	"60":  "Asia/Kuala_Lumpur",
+	"61":  "Australia/Sydney",
+	"62":  "Asia/Jakarta",
+	"63":  "Asia/Manila",
+	"64":  "Pacific/Auckland",
+	"65":  "Asia/Singapore",
+	"66":  "Asia/Bangkok",
+	"81":  "Asia/Tokyo",
+	"82":  "Asia/Seoul",
+	"84":  "Asia/Ho_Chi_Minh",
+	"86":  "Asia/Shanghai",
+	"90":  "Europe/Istanbul",
+	"91":  "Asia/Kolkata",
+	"92":  "Asia/Karachi",
+	"234": "Africa/Lagos",
+	"254": "Africa/Nairobi",
+	"351": "Europe/Lisbon",
+	"353": "Europe/Dublin",
+	"358": "Europe/Helsinki",
+	"420": "Europe/Prague",
+	"852": "Asia/Hong_Kong",
+	"886": "Asia/Taipei",
+	"971": "Asia/Dubai",
+	"972": "Asia/Jerusalem",
+}
+
+// ZoneForPhone guesses the timezone of whoever has phone, an E.164
+// number like +4915112345678, from its country calling code
+func ZoneForPhone(phone string) (string, bool) {
+	if !strings.HasPrefix(phone, "+") {
+		return "", false
+	}
+	digits := phone[1:]
+
+	// Calling codes are 1-3 digits and none is a prefix of another
+	for n := 1; n <= 3 && n <= len(digits); n++ {
+		if zone, ok := zonesByCallingCode[digits[:n]]; ok {
+			return zone, true
+		}
+	}
+	return "", false
+}
+
+// FindLocation loads the IANA timezone name, e.g. "Europe/Berlin",
+// forgiving mistakes in capitalization like "europe/berlin" or "utc"
+func FindLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+
+	loc, err := LoadLocation(name)
+	if err == nil {
+		return loc, nil
+	}
+	for _, guess := range []string{titleZone(name), strings.ToUpper(name)} {
+		if loc, err2 := LoadLocation(guess); err2 == nil {
+			return loc, nil
+		}
+	}
+	return nil, err
+}
+
+// titleZone capitalizes each word of a timezone name, e.g.
+// "america/new_york" to "America/New_York"
+func titleZone(name string) string {
+	b := []rune(strings.ToLower(name))
+	for i := range b {
+		if i == 0 || b[i-1] == '/' || b[i-1] == '_' || b[i-1] == '-' {
+			b[i] = unicode.ToUpper(b[i])
+		}
+	}
+	return string(b)
+}
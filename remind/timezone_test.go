@@ -0,0 +1,47 @@
+package remind
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZoneForPhone(t *testing.T) {
+	tests := []struct {
+		phone string
+		zone  string
+	}{
+		{"+4915112345678", "Europe/Berlin"},
+		{"+442071234567", "Europe/London"},
+		{"+353861234567", "Europe/Dublin"},
+		{"+819012345678", "Asia/Tokyo"},
+		{"+14155551234", ""}, // Too many US/Canada timezones to guess
+		{"4155551234", ""},   // Not E.164
+		{"+79161234567", ""}, // Russia spans 11 timezones
+	}
+
+	for _, test := range tests {
+		zone, ok := ZoneForPhone(test.phone)
+		assert.Equal(t, test.zone, zone, test.phone)
+		assert.Equal(t, test.zone != "", ok, test.phone)
+	}
+}
+
+func TestUserLocation(t *testing.T) {
+	u := &User{Phone: "+14155551234"}
+	assert.Equal(t, LosAngeles, u.Location())
+
+	u.Phone = "+4915112345678"
+	assert.Equal(t, "Europe/Berlin", u.Location().String())
+
+	assert.NoError(t, u.SetTimezone("america/new_york"))
+	assert.Equal(t, "America/New_York", u.Timezone)
+	assert.Equal(t, "America/New_York", u.Location().String())
+
+	assert.NoError(t, u.SetTimezone("utc"))
+	assert.Equal(t, "UTC", u.Timezone)
+
+	assert.Error(t, u.SetTimezone("Mars/Olympus_Mons"))
+	assert.Error(t, u.SetTimezone("Local"))
+	assert.Equal(t, "UTC", u.Timezone, "Failed changes are ignored")
+}
@@ -31,3 +31,17 @@ func (rems Reminders) NotCancelled() Reminders {
 
 	return notCancelled
 }
+
+// Active returns the reminders that are neither cancelled nor
+// completed
+func (rems Reminders) Active() Reminders {
+	var active Reminders
+
+	for _, rem := range rems {
+		if !rem.Cancelled && !rem.Completed {
+			active = append(active, rem)
+		}
+	}
+
+	return active
+}
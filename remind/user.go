@@ -0,0 +1,139 @@
+package remind
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+var userBucket = []byte("user")
+
+// DefaultAroundWindow is how far before or after its time a reminder
+// sent "around" then may go out, unless the user says otherwise
+const DefaultAroundWindow = 60 * time.Minute
+
+// MaxAroundWindow keeps "around" reminders within a day of their time
+const MaxAroundWindow = 12 * time.Hour
+
+// User holds the preferences of whoever texts from Phone
+type User struct {
+	Phone string
+
+	// Used by reminders sent "around" a time that don't say how far
+	// around; 0 means DefaultAroundWindow
+	AroundWindow time.Duration `json:",omitempty"`
+
+	// IANA timezone name, e.g. "Europe/Berlin". Empty means guess from
+	// Phone's country code, else LosAngeles.
+	Timezone string `json:",omitempty"`
+}
+
+// Location returns where u's times of day are interpreted
+func (u *User) Location() *time.Location {
+	if u == nil {
+		return LosAngeles
+	}
+
+	zone := u.Timezone
+	if zone == "" {
+		zone, _ = ZoneForPhone(u.Phone)
+	}
+	if zone == "" {
+		return LosAngeles
+	}
+
+	loc, err := LoadLocation(zone)
+	if err != nil {
+		log.Printf("Error loading %v's timezone %q: %v\n", u.Phone, zone, err)
+		return LosAngeles
+	}
+	return loc
+}
+
+// SetTimezone sets u's timezone to the IANA timezone name, e.g.
+// "America/New_York"
+func (u *User) SetTimezone(name string) error {
+	loc, err := FindLocation(name)
+	if err != nil {
+		return fmt.Errorf("Unknown timezone %q; try one like"+
+			" America/New_York or Europe/Berlin", name)
+	}
+	u.Timezone = loc.String()
+	return nil
+}
+
+// Around returns u's default window for "around" reminders
+func (u *User) Around() time.Duration {
+	if u == nil || u.AroundWindow == 0 {
+		return DefaultAroundWindow
+	}
+	return u.AroundWindow
+}
+
+// SetAroundWindow sets u's default window for "around" reminders
+func (u *User) SetAroundWindow(window time.Duration) error {
+	if err := CheckAroundWindow(window); err != nil {
+		return err
+	}
+	u.AroundWindow = window
+	return nil
+}
+
+// CheckAroundWindow returns an error if window is too small or large
+// to send a reminder within
+func CheckAroundWindow(window time.Duration) error {
+	if window <= 0 || window > MaxAroundWindow {
+		return fmt.Errorf("\"Around\" window must be between 1m and %v, not %v",
+			MaxAroundWindow, window)
+	}
+	return nil
+}
+
+// GetUser returns the User who texts from phone, or a new one with
+// default preferences if they haven't set any
+func GetUser(db *bolt.DB, phone string) (*User, error) {
+	u := &User{Phone: phone}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(userBucket)
+		if err != nil {
+			return err
+		}
+
+		v := b.Get([]byte(phone))
+		if v == nil {
+			return nil
+		}
+		return json.Unmarshal(v, u)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	u.Phone = phone
+	return u, nil
+}
+
+func (u *User) Save(db *bolt.DB) error {
+	if u == nil || u.Phone == "" {
+		return errors.New("Cannot save user without a phone number")
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(userBucket)
+		if err != nil {
+			return err
+		}
+
+		uBytes, err := json.Marshal(u)
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(u.Phone), uBytes)
+	})
+}
@@ -0,0 +1,53 @@
+package remind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/assert"
+)
+
+func openTestDB(t *testing.T) *bolt.DB {
+	dir, err := os.MkdirTemp("", "do_reminder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func TestUserAroundWindow(t *testing.T) {
+	db := openTestDB(t)
+
+	u, err := GetUser(db, "+15555550100")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "+15555550100", u.Phone)
+	assert.Equal(t, DefaultAroundWindow, u.Around())
+
+	assert.Error(t, u.SetAroundWindow(0))
+	assert.Error(t, u.SetAroundWindow(13*time.Hour))
+	assert.NoError(t, u.SetAroundWindow(20*time.Minute))
+	assert.NoError(t, u.Save(db))
+
+	u, err = GetUser(db, "+15555550100")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 20*time.Minute, u.Around())
+	}
+
+	u, err = GetUser(db, "+15555550199")
+	if assert.NoError(t, err) {
+		assert.Equal(t, DefaultAroundWindow, u.Around())
+	}
+}
@@ -13,9 +13,11 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf16"
 
 	"github.com/boltdb/bolt"
 	"github.com/codegangsta/martini"
@@ -24,11 +26,45 @@ import (
 )
 
 var (
-	runningReminders = &remind.ActiveReminders{}
+	scheduler *remind.Scheduler
+
+	// namedTimes are the times of day meant by "morning", "evening",
+	// etc. Each can be overridden with an env var like MORNING_TIME=7:30am
+	namedTimes = map[string]string{
+		"morning":   "09:00",
+		"afternoon": "14:00",
+		"evening":   "18:00",
+		"night":     "21:00",
+	}
 )
 
 func init() {
 	rand.Seed(remind.Now().Unix())
+
+	for name := range namedTimes {
+		envVar := strings.ToUpper(name) + "_TIME"
+		if v := os.Getenv(envVar); v != "" {
+			hhmm, err := parseClock(v)
+			if err != nil {
+				log.Printf("Ignoring %s: %v\n", envVar, err)
+				continue
+			}
+			namedTimes[name] = hhmm
+		}
+	}
+
+	// E.g., MISSED_RUNS="all 3" sends up to 3 runs missed while down
+	if v := os.Getenv("MISSED_RUNS"); v != "" {
+		policy, max, err := remind.ParseMissedPolicy(v)
+		if err != nil {
+			log.Printf("Ignoring MISSED_RUNS: %v\n", err)
+		} else {
+			remind.DefaultMissed = policy
+			if max != 0 {
+				remind.MaxMissed = max
+			}
+		}
+	}
 }
 
 func main() {
@@ -41,13 +77,26 @@ func main() {
 	}
 	defer db.Close()
 
+	if err := remind.NumberReminders(db); err != nil {
+		log.Fatalf("Error numbering reminders: %v\n", err)
+	}
+
 	// Schedule all (non-cancelled) Reminders
 	rems, err := remind.GetAllReminders(db)
 	if err != nil {
 		log.Fatalf("Error getting reminders: %v\n", err)
 	}
 
-	runningReminders.Schedule(db, rems)
+	// Reminders are queued, then sent (and retried if need be) by
+	// outbox. Replies are sent as TwiML instead; see twilioResponse.
+	outbox := remind.NewOutbox(db, twilhelp.DefaultSender)
+	outbox.Start()
+	defer outbox.Stop()
+
+	scheduler = remind.NewScheduler(db, outbox, 0)
+	scheduler.Schedule(rems)
+	scheduler.Start()
+	defer scheduler.Stop()
 
 	//
 	// Router, etc
@@ -61,201 +110,1580 @@ func main() {
 
 	m.Map(db)
 
-	r.Post("/sms", incomingSMS)
+	// Only Twilio may tell us who texted what, unless developing
+	// locally
+	devMode, _ := strconv.ParseBool(os.Getenv("DEV_MODE"))
+	if devMode {
+		log.Println("DEV_MODE set; NOT checking that requests to /sms are" +
+			" from Twilio")
+		r.Post("/sms", incomingSMS)
+	} else {
+		if twilhelp.TwilioKey == "" {
+			log.Fatalln("TWILIO_KEY must be set to check that requests to" +
+				" /sms are from Twilio (or set DEV_MODE=1 to not check)")
+		}
+		checkSignature := twilhelp.RequireSignature(twilhelp.TwilioKey,
+			os.Getenv("PUBLIC_URL"))
+		r.Post("/sms", checkSignature, incomingSMS)
+	}
 
 	m.Run()
 }
 
-func twilioResponse(s string) string {
-	return xml.Header + "<Response>\n" + s + "\n</Response>"
+// twilioResponse returns TwiML that texts each of msgs back to
+// whoever texted us
+func twilioResponse(msgs ...string) string {
+	var b strings.Builder
+	b.WriteString(xml.Header + "<Response>\n")
+	for _, msg := range msgs {
+		b.WriteString("<Message>")
+		xml.EscapeText(&b, []byte(msg))
+		b.WriteString("</Message>\n")
+	}
+	b.WriteString("</Response>")
+	return b.String()
 }
 
 // 0: (Entire message)
-// 1: (Description)
-// 2: @|at|around
-// 3: hh:mm (NextRun)
-// 4: (starting)?
-// 5: (today|tonight|tomorrow|\d?\d/\d?\d)?
-// 6: (daily)?
-var regexRemindMe = regexp.MustCompile(`^\s*[Rr]emind me (?:to|that) (.+?)\s*(@|at|around)\s*(\d?\d:?\d\d)\s*(starting)?\s*(?:on)?\s*(today|tonight|tomorrow|\d?\d/\d?\d)?\s*(daily)?`)
+// 1: (Description followed by when to send it; see scheduleClauses)
+var regexRemindMe = regexp.MustCompile(`^\s*[Rr]emind me (?:to|that) (.+)`)
 
 // 0: (Entire message)
-// 1: Reminder ID(s)
+// 1: Reminder number(s)
 var regexStopReminder = regexp.MustCompile(`(?:[Ss]top|[Dd]elete)\s*(?:[Rr]eminder)?\s*#?([\d ,]+)`)
 
+// 0: (Entire message)
+// 1: (IANA timezone name, e.g. Europe/Berlin)
+var regexTimezone = regexp.MustCompile(`(?i)^\s*(?:set\s+)?(?:my\s+)?(?:time\s*zone|tz)\s+(?:to\s+|=\s*|is\s+)?([a-z_]+(?:/[a-z0-9_+\-]+)*)[.!]*\s*$`)
+
+// 0: (Entire message)
+// 1: (Default window for "around" reminders, e.g. 20 minutes)
+var regexAroundWindow = regexp.MustCompile(`(?i)^\s*(?:set\s+)?(?:my\s+)?(?:default\s+)?(?:"?around"?\s+)?window\s+(?:to\s+|=\s*|is\s+)?(` + offsetPattern + `)[.!]*\s*$`)
+
+// 0: (Entire message)
+// 1: (Minutes to snooze for, if just a number)
+// 2: (How long to snooze for otherwise; see offsetPattern)
+var regexSnooze = regexp.MustCompile(`(?i)^\s*(?:snooze|later)(?:\s+(?:for\s+)?(?:(\d+)|(` + offsetPattern + `)))?\s*[.!]*\s*$`)
+
+// 0: (Entire message)
+// 1: (Reminder number, if given)
+var regexDone = regexp.MustCompile(`(?i)^\s*done(?:\s+(?:with\s+)?(?:reminder\s*)?#?(\d+))?\s*[.!]*\s*$`)
+
+// 0: (Entire message)
+// 1: yes|no|stop
+// 2: (Phone number of whose alerts it's about, if given)
+var regexConsent = regexp.MustCompile(`(?i)^\s*(yes|no|stop)(?:\s+(?:alerts\s+)?(?:from\s+)?(\+?[\d(][\d\-.() ]*\d))?\s*[.!]*\s*$`)
+
+// 0: (Entire message)
+var regexList = regexp.MustCompile(`(?i)^\s*(?:list|(?:list |show )?(?:my )?reminders)(?: please)?[.!?]*\s*$`)
+
 func incomingSMS(db *bolt.DB, req *http.Request, log *log.Logger) string {
 	from := req.FormValue("From")
 	body := req.FormValue("Body")
 
 	log.Printf("Incoming SMS: `%v: %v`", from, body)
 
-	parts := regexStopReminder.FindStringSubmatch(body)
+	// Before "stop 12", which could mean reminder 12
+	parts := regexConsent.FindStringSubmatch(body)
+	if len(parts) > 0 {
+		if reply, ok := handleConsent(db, from, parts[1], parts[2]); ok {
+			return reply
+		}
+	}
+
+	parts = regexStopReminder.FindStringSubmatch(body)
 	if len(parts) > 0 {
 		return handleCancel(db, from, parts[1])
 	}
 
+	user, err := remind.GetUser(db, from)
+	if err != nil {
+		log.Printf("Error getting user %v: %v\n", from, err)
+		return twilioResponse("Error looking up your settings. Sorry!")
+	}
+
+	parts = regexSnooze.FindStringSubmatch(body)
+	if len(parts) > 0 {
+		return handleSnooze(db, user, parts[1], parts[2])
+	}
+
+	parts = regexDone.FindStringSubmatch(body)
+	if len(parts) > 0 {
+		return handleDone(db, user, parts[1])
+	}
+
+	if regexList.MatchString(body) {
+		return handleList(db, user)
+	}
+
+	parts = regexTimezone.FindStringSubmatch(body)
+	if len(parts) > 0 {
+		return handleTimezone(db, user, parts[1])
+	}
+
+	parts = regexAroundWindow.FindStringSubmatch(body)
+	if len(parts) > 0 {
+		return handleAroundWindow(db, user, parts[1])
+	}
+
 	// Remind me to _ @ _
 
-	reminder, err := parseReminder(from, body)
+	reminder, err := parseReminder(user, body)
 	if err != nil {
 		log.Printf("Error parsing incoming message body: %v\n", err)
-		return twilioResponse("")
+		return twilioResponse(err.Error())
 	}
 
 	err = reminder.Save(db)
 	if err != nil {
-		if err != nil {
-			log.Printf("Error saving reminder %#v: %v\n", reminder, err)
-		}
+		log.Printf("Error saving reminder %#v: %v\n", reminder, err)
+		return twilioResponse("Error saving your reminder. Sorry!")
+	}
 
-		err2 := twilhelp.SendSMS(from, "Error saving your reminder. Sorry!")
-		if err2 != nil {
-			log.Printf(`Error sending "sorry we couldn't save" msg: %v\n`, err)
-		}
+	err = scheduler.Add(reminder)
+	if err != nil {
+		log.Printf("Error scheduling reminder %#v: %v\n", reminder, err)
+		return twilioResponse("Error scheduling your reminder. Sorry!")
+	}
 
-		return twilioResponse("")
+	// Nobody's told about it until they agree to be
+	err = scheduler.AskToEscalate(reminder)
+	if err != nil {
+		log.Printf("Error asking to escalate reminder %#v: %v\n", reminder,
+			err)
 	}
 
-	err = runningReminders.ScheduleNew(db, reminder)
+	return twilioResponse(fmt.Sprintf("Reminder %v successfully scheduled"+
+		" for %s! Have a great day :-)", reminder.Number,
+		reminder.Nominal.In(user.Location()).Format(replyTimeFormat)))
+}
+
+// handleConsent records whether from agrees to be told when the
+// recipient with phone number owner (or if empty, everyone who asked
+// them) doesn't do their reminders. ok is false if nobody asked, so
+// the message means something else.
+func handleConsent(db *bolt.DB, from, answer, owner string) (reply string, ok bool) {
+	requests, err := remind.ConsentRequests(db, from)
 	if err != nil {
-		log.Printf("Error scheduling reminder %#v: %v\n", reminder, err)
-		err2 := twilhelp.SendSMS(from, "Error scheduling your reminder. Sorry!")
-		if err2 != nil {
-			log.Printf("Error after successful parse but failed scheduling: %v\n", err2)
+		log.Printf("Error getting %v's escalation requests: %v\n", from, err)
+		return "", false
+	}
+	yes := strings.EqualFold(answer, "yes")
+
+	var owners []string
+	if owner != "" {
+		owner = twilhelp.CleanNumber(owner)
+		if _, asked := requests[owner]; !asked {
+			return "", false
+		}
+		owners = append(owners, owner)
+	} else {
+		// "YES" answers who's waiting; "STOP", everyone
+		for o, consent := range requests {
+			if consent == remind.ConsentAsked ||
+				(!yes && consent == remind.ConsentGiven) {
+				owners = append(owners, o)
+			}
 		}
-		return twilioResponse("")
+		if len(owners) == 0 {
+			return "", false
+		}
+		sort.Strings(owners)
 	}
 
-	reply := fmt.Sprintf("Reminder %v successfully scheduled! "+
-		"Have a great day :-)", reminder.ID)
-	err = twilhelp.SendSMS(from, reply)
-	if err != nil {
-		log.Printf("Error from post-successful scheduling send: %v\n", err)
+	consent := remind.ConsentRefused
+	if yes {
+		consent = remind.ConsentGiven
+	}
+	for _, o := range owners {
+		if err := remind.SetConsent(db, from, o, consent); err != nil {
+			log.Printf("Error saving %v's answer to %v: %v\n", from, o, err)
+			return twilioResponse("Error saving your answer. Sorry!"), true
+		}
 	}
 
-	return twilioResponse("")
+	who := strings.Join(owners, ", ")
+	if yes {
+		return twilioResponse(fmt.Sprintf("You'll be texted when %s doesn't"+
+			" confirm their reminders. Reply STOP to stop.", who)), true
+	}
+	return twilioResponse(fmt.Sprintf("You won't be texted about %s's"+
+		" reminders.", who)), true
 }
 
-func handleCancel(db *bolt.DB, from, idStr string) string {
-	idStr = strings.ReplaceAll(idStr, ",", " ")
-	idStrs := strings.Split(idStr, " ")
+func handleCancel(db *bolt.DB, from, numStr string) string {
+	numStr = strings.ReplaceAll(numStr, ",", " ")
+	numStrs := strings.Split(numStr, " ")
 
-	var goodIds []uint64
+	var nums []uint64
 
-	for i, idStr := range idStrs {
-		if idStr == "" {
+	for i, numStr := range numStrs {
+		if numStr == "" {
 			continue
 		}
-		id, err := strconv.ParseUint(idStr, 10, 64)
+		num, err := strconv.ParseUint(numStr, 10, 64)
 		if err != nil {
 			if i == 0 {
-				log.Printf("Error parsing Reminder ID: %v\n", err)
+				log.Printf("Error parsing Reminder number: %v\n", err)
+				return twilioResponse("Error parsing the Reminder number. Sorry!")
+			}
 
-				err2 := twilhelp.SendSMS(from, "Error parsing the Reminder ID. Sorry!")
-				if err2 != nil {
-					log.Printf(`Error sending "sorry we couldn't parse" msg: %v\n`, err)
-				}
+			continue
+		}
+
+		nums = append(nums, num)
+	}
+
+	if len(nums) == 0 {
+		return twilioResponse("Error parsing the Reminder number. Sorry!")
+	}
+
+	// Users refer to their reminders by Number
+	errs := make([]error, len(nums))
+	var ids []uint64
+	var found []int // Where in nums each of ids is
+	for i, num := range nums {
+		id, err := remind.ReminderID(db, from, num)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		ids = append(ids, id)
+		found = append(found, i)
+	}
 
-				return twilioResponse("")
+	// Only the recipient of a reminder may stop it
+	for j, err := range scheduler.CancelFor(from, ids) {
+		errs[found[j]] = err
+	}
+
+	lines := make([]string, len(nums))
+	stopped := 0
+	for i, num := range nums {
+		switch errs[i] {
+		case nil:
+			lines[i] = fmt.Sprintf("Reminder %v stopped", num)
+			stopped++
+		case remind.ErrReminderNotFound:
+			lines[i] = fmt.Sprintf("Reminder %v not found", num)
+		case remind.ErrNotOwner:
+			lines[i] = fmt.Sprintf("Reminder %v isn't yours", num)
+		case remind.ErrAlreadyStopped:
+			lines[i] = fmt.Sprintf("Reminder %v was already stopped", num)
+		default:
+			log.Printf("Error cancelling %v's Reminder %v: %v\n", from, num,
+				errs[i])
+			lines[i] = fmt.Sprintf("Error stopping Reminder %v. Sorry!", num)
+		}
+	}
+	if stopped == len(nums) {
+		lines[len(lines)-1] += ". Have an epic day!"
+	}
+
+	return twilioResponse(paginate(lines, smsLength, smsLengthUCS2)...)
+}
+
+// handleSnooze sends the last reminder sent to user again in mins
+// minutes, or after offset, or remind.DefaultSnooze
+func handleSnooze(db *bolt.DB, user *remind.User, mins, offset string) string {
+	d := remind.DefaultSnooze
+	var err error
+	switch {
+	case mins != "":
+		var n int
+		n, err = strconv.Atoi(mins)
+		d = time.Duration(n) * time.Minute
+	case offset != "":
+		d, err = parseOffset(offset)
+	}
+	if err != nil {
+		return twilioResponse(fmt.Sprintf("Error snoozing: %v", err))
+	}
+
+	sent, err := remind.LastSent(db, user.Phone)
+	if err != nil {
+		if err != remind.ErrNothingSent {
+			log.Printf("Error getting what was last sent to %v: %v\n",
+				user.Phone, err)
+		}
+		return twilioResponse("Error snoozing: " + err.Error())
+	}
+
+	at, err := scheduler.Snooze(user.Phone, sent.ReminderID, d)
+	switch err {
+	case nil:
+		return twilioResponse(fmt.Sprintf("Snoozed Reminder %v until %s",
+			sent.Number, at.In(user.Location()).Format("3:04pm MST")))
+	case remind.ErrAlreadyStopped:
+		return twilioResponse(fmt.Sprintf("Reminder %v was stopped, so it"+
+			" can't be snoozed", sent.Number))
+	}
+	log.Printf("Error snoozing %v's Reminder %v: %v\n", user.Phone,
+		sent.Number, err)
+	return twilioResponse(fmt.Sprintf("Error snoozing Reminder %v: %v",
+		sent.Number, err))
+}
+
+// handleDone records that user did what the reminder numbered numStr
+// (or if empty, the last one sent to them) reminded them to, so it
+// stops being sent again
+func handleDone(db *bolt.DB, user *remind.User, numStr string) string {
+	var id, num uint64
+	if numStr == "" {
+		sent, err := remind.LastSent(db, user.Phone)
+		if err != nil {
+			if err != remind.ErrNothingSent {
+				log.Printf("Error getting what was last sent to %v: %v\n",
+					user.Phone, err)
 			}
+			return twilioResponse(err.Error())
+		}
+		id, num = sent.ReminderID, sent.Number
+	} else {
+		var err error
+		num, err = strconv.ParseUint(numStr, 10, 64)
+		if err != nil {
+			return twilioResponse("Error parsing the Reminder number. Sorry!")
+		}
+		id, err = remind.ReminderID(db, user.Phone, num)
+		if err == remind.ErrReminderNotFound {
+			return twilioResponse(fmt.Sprintf("Reminder %v not found", num))
+		}
+		if err != nil {
+			log.Printf("Error getting %v's Reminder %v: %v\n", user.Phone,
+				num, err)
+			return twilioResponse(fmt.Sprintf("Error marking Reminder %v"+
+				" done. Sorry!", num))
+		}
+	}
+
+	_, err := scheduler.Ack(user.Phone, id)
+	switch err {
+	case nil:
+		return twilioResponse(fmt.Sprintf("Reminder %v marked done. Nice"+
+			" work!", num))
+	case remind.ErrNothingPending:
+		return twilioResponse(fmt.Sprintf("Reminder %v isn't waiting for"+
+			" you to say it's done", num))
+	case remind.ErrNotOwner:
+		return twilioResponse(fmt.Sprintf("Reminder %v isn't yours", num))
+	}
+	log.Printf("Error marking %v's Reminder %v done: %v\n", user.Phone, num,
+		err)
+	return twilioResponse(fmt.Sprintf("Error marking Reminder %v done. Sorry!",
+		num))
+}
+
+// replyTimeFormat is how times are written in replies
+const replyTimeFormat = "Mon Jan 2 3:04pm MST"
 
+// smsLength is how long a reply can be before it'd be split into
+// multiple SMS segments, when it's all GSM-7 characters; smsLengthUCS2
+// is how long it can be when anything else (emoji, many accents)
+// forces it to be sent as UCS-2
+const (
+	smsLength     = 160
+	smsLengthUCS2 = 70
+)
+
+// gsm7 and gsm7Ext are the GSM-7 alphabet; each of gsm7Ext takes two
+// characters of a segment
+const (
+	gsm7 = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+		"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+	gsm7Ext = "^{}\\[~]|€\f"
+)
+
+// smsSize returns how many characters of a segment msg takes, and
+// which of gsmMax and ucs2Max applies given how it'd be encoded
+func smsSize(msg string, gsmMax, ucs2Max int) (size, max int) {
+	for _, r := range msg {
+		switch {
+		case strings.ContainsRune(gsm7, r):
+			size++
+		case strings.ContainsRune(gsm7Ext, r):
+			size += 2
+		default:
+			return len(utf16.Encode([]rune(msg))), ucs2Max
+		}
+	}
+	return size, gsmMax
+}
+
+func handleList(db *bolt.DB, user *remind.User) string {
+	rems, err := remind.GetRemindersFor(db, user.Phone)
+	if err != nil {
+		log.Printf("Error getting %v's reminders: %v\n", user.Phone, err)
+		return twilioResponse("Error getting your reminders. Sorry!")
+	}
+
+	rems = rems.Active()
+	if len(rems) == 0 {
+		return twilioResponse("You have no reminders. Text something like" +
+			" \"Remind me to take out the trash @ 6pm daily\" to add one")
+	}
+
+	lines := make([]string, len(rems))
+	for i, r := range rems {
+		next := r.Nominal
+		if next.IsZero() {
+			next = r.NextRun
+		}
+		lines[i] = fmt.Sprintf("#%d %s: %s, %s", r.Num(), r.Description,
+			next.In(user.Location()).Format(replyTimeFormat), r.Repetition())
+
+		// So they know they may have missed some
+		failed, err := remind.FailedMessages(db, r.ID)
+		if err != nil {
+			log.Printf("Error getting Reminder %v's failed messages: %v\n",
+				r.ID, err)
 			continue
 		}
+		if len(failed) > 0 {
+			lines[i] += fmt.Sprintf(" (%d failed to send)", len(failed))
+		}
+	}
 
-		goodIds = append(goodIds, id)
+	return twilioResponse(paginate(lines, smsLength, smsLengthUCS2)...)
+}
+
+// paginate joins lines into as few messages as it can, each fitting in
+// one segment: gsmMax characters if it's all GSM-7, else ucs2Max. They
+// are numbered if there's more than one. Lines too long to fit in a
+// message on their own are truncated.
+func paginate(lines []string, gsmMax, ucs2Max int) []string {
+	// Room for "(10/12)\n"
+	const numbering = 8
+	fits := func(msg string) bool {
+		size, max := smsSize(msg, gsmMax, ucs2Max)
+		return size <= max-numbering
 	}
 
-	if err := runningReminders.Cancel(db, goodIds); err != nil {
-		//log.Printf("Error cancelling Reminder %v: %v\n", id, err)
+	var msgs []string
+	msg := ""
+	for _, line := range lines {
+		if !fits(line) {
+			runes := []rune(line)
+			for len(runes) > 0 && !fits(string(runes)+"...") {
+				runes = runes[:len(runes)-1]
+			}
+			line = string(runes) + "..."
+		}
 
-		reply := fmt.Sprintf("Error stopping Reminder(s) %v. Sorry!", goodIds)
-		err2 := twilhelp.SendSMS(from, reply)
-		if err2 != nil {
-			log.Printf(`Error sending "sorry we couldn't cancel" msg: %v\n`, err)
+		switch {
+		case msg == "":
+			msg = line
+		case fits(msg + "\n" + line):
+			msg += "\n" + line
+		default:
+			msgs = append(msgs, msg)
+			msg = line
 		}
+	}
+	if msg != "" {
+		msgs = append(msgs, msg)
+	}
 
-		return twilioResponse("")
+	if len(msgs) > 1 {
+		for i := range msgs {
+			msgs[i] = fmt.Sprintf("(%d/%d)\n%s", i+1, len(msgs), msgs[i])
+		}
 	}
+	return msgs
+}
 
-	reply := fmt.Sprintf("Reminder(s) %v successfully stopped. Have an epic day!", goodIds)
-	err := twilhelp.SendSMS(from, reply)
+func handleTimezone(db *bolt.DB, user *remind.User, name string) string {
+	err := user.SetTimezone(name)
+	if err == nil {
+		err = user.Save(db)
+	}
 	if err != nil {
-		log.Printf(`Error sending "reminder %v successfully stopped": %v\n`, goodIds,
-			err)
+		log.Printf("Error setting %v's timezone: %v\n", user.Phone, err)
+		return twilioResponse(fmt.Sprintf("Error setting your timezone: %v",
+			err))
 	}
-	return twilioResponse("")
+
+	return twilioResponse(fmt.Sprintf("Your timezone is now %s (it's %s"+
+		" there). New reminders will use it.", user.Timezone,
+		remind.Now().In(user.Location()).Format("3:04pm MST")))
 }
 
-func parseReminder(from, body string) (*remind.Reminder, error) {
-	parts := regexRemindMe.FindStringSubmatch(body)
-	if len(parts) < 7 {
-		err := errors.New("Could not schedule your reminder. Be sure to" +
-			" use military time (24-hour time) when saying something like," +
-			"\n\nRemind me to take out the trash @ 18:00 daily")
-		log.Printf("Error sending after failed time parsing: %v\n", err)
-		return nil, err
+func handleAroundWindow(db *bolt.DB, user *remind.User, windowStr string) string {
+	window, err := parseOffset(windowStr)
+	if err == nil {
+		err = user.SetAroundWindow(window)
 	}
+	if err == nil {
+		err = user.Save(db)
+	}
+	if err != nil {
+		log.Printf("Error setting %v's around window: %v\n", user.Phone, err)
+		return twilioResponse(fmt.Sprintf(
+			"Error setting your \"around\" window: %v", err))
+	}
+
+	return twilioResponse(fmt.Sprintf("Reminders \"around\" a time will now"+
+		" go out up to %s before or after it", user.Around()))
+}
 
-	// len(parts) >= 7
+var errParseReminder = errors.New("Could not schedule your reminder. Be sure to" +
+	" include a time (like 18:00, 6pm, noon, or in 20 minutes) when saying" +
+	" something like,\n\nRemind me to take out the trash @ 6pm daily")
 
-	// log.Printf("%d parts == %#v\n", len(parts), parts)
+func parseReminder(user *remind.User, body string) (*remind.Reminder, error) {
+	parts := regexRemindMe.FindStringSubmatch(body)
+	if len(parts) < 2 {
+		log.Printf("Error sending after failed time parsing: %v\n", errParseReminder)
+		return nil, errParseReminder
+	}
+
+	loc := user.Location()
 
 	// parts[0] is the entire SMS message; ignore
-	description := parts[1]
-	around := (parts[2] == "around")
-	nextRun, err := parseTime(parts[3], parts[5])
+	description, spec, err := splitSchedule(parts[1], loc)
+	if err != nil {
+		return nil, err
+	}
+
+	var nextRun time.Time
+	if spec.cron != "" {
+		nextRun, err = spec.cronStart()
+	} else {
+		nextRun, err = spec.nextRun()
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	impliedDaily := (parts[4] == "starting")
+	// E.g., "monthly starting 1/31" runs on the 31st (or the last day)
+	// of each month
+	if rec := spec.recurrence; rec != nil && rec.Freq != remind.Weekly {
+		if rec.MonthDay == 0 && rec.Nth == 0 {
+			rec.MonthDay = nextRun.Day()
+		}
+		if rec.Freq == remind.Yearly && rec.Month == 0 {
+			rec.Month = nextRun.Month()
+		}
+	}
+
+	// E.g., "on the 5th at 9am" when today is the 6th
+	if spec.onDay != nil && !spec.onDay.Includes(nextRun) {
+		nextRun = spec.onDay.Next(nextRun)
+	}
+
+	// E.g., "@ 18:00 every Monday" when today is Sunday
+	if spec.recurrence != nil && !spec.recurrence.Includes(nextRun) {
+		nextRun = spec.recurrence.Next(nextRun)
+	}
 
-	var period time.Duration
-	if impliedDaily || parts[6] == "daily" {
+	// "starting" with nothing else implies daily
+	period := spec.period
+	if spec.repeat == "daily" || (spec.starting && spec.repeat == "") {
 		period = 24 * time.Hour
 	}
 
 	var plusMinus time.Duration
-	if around {
-		// TODO: Make configurable
-		plusMinus = 60 * time.Minute
+	switch {
+	case spec.window != 0:
+		plusMinus = spec.window
+	case spec.around:
+		plusMinus = user.Around()
+	}
+
+	// Otherwise "every 90 minutes" could send twice in a row
+	if period != 0 && 2*plusMinus >= period {
+		return nil, fmt.Errorf("Your reminder repeats every %s, too often"+
+			" to send it \"around\" a time (+/- %s)", period, plusMinus)
 	}
 
 	reminder := &remind.Reminder{
-		Recipient:   from,
+		Recipient:   user.Phone,
 		Description: strings.ToUpper(description[0:1]) + description[1:],
 		NextRun:     nextRun,
+		Timezone:    loc.String(),
 		Period:      period,
+		Recurrence:  spec.recurrence,
 		PlusMinus:   plusMinus,
 
 		Raw:     body,
 		Created: remind.Now(),
 	}
 
+	if reminder.Repeats() {
+		reminder.Start = nextRun
+	}
+
+	if spec.rrule != "" {
+		if err := reminder.SetRRule(spec.rrule); err != nil {
+			return nil, err
+		}
+	}
+	if spec.cron != "" {
+		if err := reminder.SetCron(spec.cron); err != nil {
+			return nil, err
+		}
+	}
+
+	if spec.until != "" || spec.times != 0 {
+		if !reminder.Repeats() {
+			return nil, errors.New("Only repeating reminders can end" +
+				" \"until\" a date or after a number of times")
+		}
+		if spec.until != "" {
+			reminder.Until, err = parseUntil(spec.until, reminder.NextRun)
+			if err != nil {
+				return nil, err
+			}
+		}
+		reminder.MaxRuns = spec.times
+	}
+
+	reminder.EscalateTo = spec.escalateTo
+	reminder.EscalateAfter = spec.escalateAfter
+	reminder.RecipientName = spec.recipientName
+	if after := reminder.EscalateAfter; len(reminder.EscalateTo) > 0 {
+		if after == 0 {
+			after = remind.DefaultEscalateAfter
+		}
+		// Otherwise its next run would come first
+		if period != 0 && after >= period {
+			return nil, fmt.Errorf("Your reminder repeats every %s, so"+
+				" others can't be told it wasn't done after %s", period,
+				after)
+		}
+	}
+
+	if spec.untilDone {
+		reminder.NagEvery = spec.nagEvery
+		if reminder.NagEvery == 0 {
+			reminder.NagEvery = remind.DefaultNagEvery
+		}
+		if reminder.NagEvery < remind.MinNagEvery {
+			return nil, fmt.Errorf("Your reminder can't be sent again more"+
+				" often than every %s", remind.MinNagEvery)
+		}
+		if period != 0 && reminder.NagEvery >= period {
+			return nil, fmt.Errorf("Your reminder repeats every %s, so it"+
+				" can't also be sent again every %s until it's done",
+				period, reminder.NagEvery)
+		}
+	}
+
 	return reminder, nil
 }
 
-func parseTime(hhmm string, day string) (time.Time, error) {
-	when := strings.SplitN(hhmm, ":", 2)
-	hours, _ := strconv.Atoi(when[0])
-	mins, _ := strconv.Atoi(when[1])
+// scheduleSpec accumulates the parts of a reminder's schedule as each
+// clause following its description is parsed
+type scheduleSpec struct {
+	loc *time.Location // Where times of day are interpreted
 
-	now := remind.Now()
+	hhmm     string        // Time of day, if given
+	offset   time.Duration // Time from now, if relative
+	relative bool
+	day      string // today|tonight|tomorrow|mm/dd
+	starting bool
+	around   bool
+	window   time.Duration // How far "around" its time, if given
 
-	if day == "" || day == "today" || day == "tonight" || day == "tomorrow" {
-		nowHours, nowMins, nowSecs := now.Clock()
-		today := now.
-			Add(time.Duration(-nowHours) * time.Hour).
-			Add(time.Duration(-nowMins) * time.Minute).
-			Add(time.Duration(-nowSecs) * time.Second)
+	repeat     string // daily|weekly|monthly|yearly|rrule|cron|period
+	period     time.Duration
+	recurrence *remind.Recurrence
+	rrule      string
+	cron       string
+
+	// When repeating reminders end
+	until string // mm/dd
+	times int
+
+	// Whether it's sent again (every nagEvery, if given) until it's done
+	untilDone bool
+	nagEvery  time.Duration
+
+	// Who's told if it isn't done in time, and what they call the
+	// recipient
+	escalateTo    []string
+	escalateAfter time.Duration
+	recipientName string
+
+	// For monthly and yearly repeats
+	interval   int
+	monthDay   int
+	nth        int
+	nthWeekday time.Weekday
+
+	// The day of the month a reminder that doesn't repeat is sent on,
+	// if given
+	onDay *remind.Recurrence
+}
+
+func (spec *scheduleSpec) setRepeat(repeat string) error {
+	if spec.repeat != "" && spec.repeat != repeat {
+		return fmt.Errorf("Your reminder can't repeat both %s and %s",
+			spec.repeat, repeat)
+	}
+	spec.repeat = repeat
+	return nil
+}
+
+func (spec *scheduleSpec) setPeriod(period time.Duration) error {
+	if err := spec.setRepeat("period"); err != nil {
+		return err
+	}
+	if spec.period != 0 && spec.period != period {
+		return errors.New("Your reminder can only repeat every so often once")
+	}
+	// Too short a period is checked by finish, as it's fine for
+	// reminders sent again until they're done
+	spec.period = period
+	return nil
+}
+
+// setUntilDone makes the reminder be sent again every offset (or
+// remind.DefaultNagEvery, if empty) until it's done
+func (spec *scheduleSpec) setUntilDone(offset string) error {
+	if spec.untilDone {
+		return errors.New("Your reminder can only say \"until done\" once")
+	}
+	spec.untilDone = true
+	if offset == "" {
+		return nil
+	}
+
+	nagEvery, err := parseOffset(offset)
+	if err != nil {
+		return err
+	}
+	if nagEvery < remind.MinNagEvery {
+		return fmt.Errorf("Your reminder can't be sent again more often"+
+			" than every %s", remind.MinNagEvery)
+	}
+	spec.nagEvery = nagEvery
+	return nil
+}
+
+// addEscalation has phone texted if the reminder isn't done within
+// offset (or remind.DefaultEscalateAfter, if empty), calling its
+// recipient name, if given
+func (spec *scheduleSpec) addEscalation(phone, offset, name string) error {
+	if offset != "" {
+		after, err := parseOffset(offset)
+		if err != nil {
+			return err
+		}
+		if spec.escalateAfter != 0 && spec.escalateAfter != after {
+			return errors.New("Your reminder can only say how long until" +
+				" others are told once")
+		}
+		spec.escalateAfter = after
+	}
+	if name != "" {
+		if spec.recipientName != "" && spec.recipientName != name {
+			return errors.New("Your reminder can only say what others call" +
+				" you once")
+		}
+		spec.recipientName = name
+	}
+
+	phone = twilhelp.CleanNumber(phone)
+	for _, to := range spec.escalateTo {
+		if to == phone {
+			return nil
+		}
+	}
+	if len(spec.escalateTo) == remind.MaxEscalateTo {
+		return fmt.Errorf("Your reminder can only tell up to %d others",
+			remind.MaxEscalateTo)
+	}
+	spec.escalateTo = append(spec.escalateTo, phone)
+	return nil
+}
+
+type scheduleClause struct {
+	re    *regexp.Regexp
+	parse func(spec *scheduleSpec, parts []string) error
+}
+
+// Matches relative offsets like "45 minutes", "1h30m", "an hour", and
+// "2 days and 3 hours"
+const offsetPattern = `(?:(?:\d+\s*|\ban?\s+)(?:weeks?|days?|hours?|hrs?|minutes?|mins?|w|d|h|m)\s*,?\s*(?:and\s+)?)+`
+
+// 0: (Entire offset)
+// 1: \d+|an?
+// 2: (Unit)
+var regexOffsetUnit = regexp.MustCompile(`(?i)(\d+|\ban?\s)\s*(weeks?|days?|hours?|hrs?|minutes?|mins?|w|d|h|m)`)
+
+// scheduleClauses are the pieces that may follow a reminder's
+// description, in any order, e.g. "around in 2 hours", "@ 18:00",
+// "starting", "on 1/1", "daily"
+var scheduleClauses = []scheduleClause{
+	// 1: @|at|around
+	// 2: (Time of day; see clockPattern)
+	{regexp.MustCompile(`(?i)^(@|at|around)\s*(` + clockPattern + `)`),
+		func(spec *scheduleSpec, parts []string) error {
+			if err := spec.setClock(parts[2]); err != nil {
+				return err
+			}
+			spec.around = spec.around || strings.EqualFold(parts[1], "around")
+			return nil
+		}},
 
-		nextRun := today.
-			Add(time.Duration(hours) * time.Hour).
-			Add(time.Duration(mins) * time.Minute)
+	// 1: (morning|afternoon|evening|night)
+	{regexp.MustCompile(`(?i)^(?:in\s+the\s+|this\s+)?(morning|afternoon|evening|night)\b`),
+		func(spec *scheduleSpec, parts []string) error {
+			return spec.setClock(parts[1])
+		}},
+
+	// 1: (around)?
+	// 2: (Offset)
+	{regexp.MustCompile(`(?i)^(around\s+)?in\s+(` + offsetPattern + `)`),
+		func(spec *scheduleSpec, parts []string) error {
+			if spec.relative {
+				return errors.New("Your reminder can only say \"in ...\" once")
+			}
+			offset, err := parseOffset(parts[2])
+			if err != nil {
+				return err
+			}
+			spec.offset = offset
+			spec.relative = true
+			spec.around = spec.around || parts[1] != ""
+			return nil
+		}},
+
+	// 1: (Offset)
+	{regexp.MustCompile(`(?i)^(?:give\s+or\s+take|plus\s+or\s+minus|±|\+/-|\+-)\s*(` + offsetPattern + `)`),
+		func(spec *scheduleSpec, parts []string) error {
+			if spec.window != 0 {
+				return errors.New("Your reminder can only say how far" +
+					" \"around\" its time once")
+			}
+			window, err := parseOffset(parts[1])
+			if err != nil {
+				return err
+			}
+			if err := remind.CheckAroundWindow(window); err != nil {
+				return err
+			}
+			spec.window = window
+			spec.around = true
+			return nil
+		}},
+
+	{regexp.MustCompile(`(?i)^starting\b`),
+		func(spec *scheduleSpec, parts []string) error {
+			spec.starting = true
+			return nil
+		}},
+
+	// 1: (today|tonight|tomorrow|\d?\d/\d?\d)
+	{regexp.MustCompile(`(?i)^(?:on\s+)?(today|tonight|tomorrow|\d?\d/\d?\d)\b`),
+		func(spec *scheduleSpec, parts []string) error {
+			return spec.setDay(strings.ToLower(parts[1]))
+		}},
+
+	// 1: (every|each)?
+	// 2: (Month name)
+	// 3: (Day of month)
+	{regexp.MustCompile(`(?i)^(?:(every|each)\s+|on\s+)?(` + monthPattern + `)\s+(\d?\d)(?:st|nd|rd|th)?\b`),
+		func(spec *scheduleSpec, parts []string) error {
+			month := monthsByPrefix[strings.ToLower(parts[2][:3])]
+			day, _ := strconv.Atoi(parts[3])
+			if day < 1 || day > 31 {
+				return fmt.Errorf("Invalid day of the month: %s", parts[3])
+			}
+			if parts[1] != "" {
+				if err := spec.setRepeat("yearly"); err != nil {
+					return err
+				}
+			}
+			return spec.setDay(fmt.Sprintf("%d/%d", month, day))
+		}},
+
+	{regexp.MustCompile(`(?i)^(?:yearly|annually|(?:every|each)\s+year)\b`),
+		func(spec *scheduleSpec, parts []string) error {
+			return spec.setRepeat("yearly")
+		}},
+
+	// 1: (every|each)?
+	// 2: (first|second|...|last)
+	// 3: (Day of week)
+	{regexp.MustCompile(`(?i)^(?:on\s+)?(?:the\s+|(every|each)\s+)?(` + nthPattern + `)\s+(` + weekdayPattern + `)`),
+		func(spec *scheduleSpec, parts []string) error {
+			if spec.monthDay != 0 || spec.nth != 0 {
+				return errors.New("Your reminder can only have one day of the month")
+			}
+			if parts[1] != "" {
+				if err := spec.setRepeat("monthly"); err != nil {
+					return err
+				}
+			}
+			spec.nth = nthByName[strings.ToLower(parts[2])]
+			spec.nthWeekday = weekdaysByPrefix[strings.ToLower(parts[3][:3])]
+			return nil
+		}},
+
+	// 1: (Day of month|last)
+	{regexp.MustCompile(`(?i)^(?:on\s+)?the\s+(\d?\d(?:st|nd|rd|th)?|last)(?:\s+day)?\b`),
+		func(spec *scheduleSpec, parts []string) error {
+			if spec.monthDay != 0 || spec.nth != 0 {
+				return errors.New("Your reminder can only have one day of the month")
+			}
+			if strings.EqualFold(parts[1], "last") {
+				spec.monthDay = -1
+				return nil
+			}
+			day, _ := strconv.Atoi(strings.TrimRight(parts[1], "stndrhSTNDRH"))
+			if day < 1 || day > 31 {
+				return fmt.Errorf("Invalid day of the month: %s", parts[1])
+			}
+			spec.monthDay = day
+			return nil
+		}},
+
+	// 1: (other)?
+	{regexp.MustCompile(`(?i)^(?:of\s+)?(?:(?:every|each)\s+(other\s+)?|the\s+)month\b`),
+		func(spec *scheduleSpec, parts []string) error {
+			if parts[1] != "" {
+				spec.interval = 2
+			}
+			return spec.setRepeat("monthly")
+		}},
+
+	{regexp.MustCompile(`(?i)^monthly\b`),
+		func(spec *scheduleSpec, parts []string) error {
+			return spec.setRepeat("monthly")
+		}},
+
+	// 1: (RRULE, e.g. FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10)
+	{regexp.MustCompile(`(?i)^(RRULE:\s*[A-Z0-9=;,+\-]+|FREQ=[A-Z0-9=;,+\-]+)`),
+		func(spec *scheduleSpec, parts []string) error {
+			if err := spec.setRepeat("rrule"); err != nil {
+				return err
+			}
+			if _, err := remind.ParseRRule(parts[1], spec.loc); err != nil {
+				return err
+			}
+			spec.rrule = parts[1]
+			return nil
+		}},
+
+	// 1: (Cron expression, e.g. 0 9 * * 1-5)
+	{regexp.MustCompile(`(?i)^cron\s+(@[a-z]+|\S+\s+\S+\s+\S+\s+\S+\s+\S+)`),
+		func(spec *scheduleSpec, parts []string) error {
+			if err := spec.setRepeat("cron"); err != nil {
+				return err
+			}
+			if _, err := remind.ParseCron(parts[1]); err != nil {
+				return err
+			}
+			spec.cron = parts[1]
+			return nil
+		}},
+
+	{regexp.MustCompile(`(?i)^(?:daily|every\s+day)\b`),
+		func(spec *scheduleSpec, parts []string) error {
+			return spec.setRepeat("daily")
+		}},
+
+	// 1: (\d+|other)?
+	// 2: (Unit)
+	{regexp.MustCompile(`(?i)^(?:every|each)\s+(?:(\d+|other)\s*)?(weeks?|days?|hours?|hrs?|minutes?|mins?)\b`),
+		func(spec *scheduleSpec, parts []string) error {
+			n := 1
+			switch strings.ToLower(parts[1]) {
+			case "":
+			case "other":
+				n = 2
+			default:
+				n, _ = strconv.Atoi(parts[1])
+			}
+			if n < 1 {
+				return fmt.Errorf("Invalid number of %s: %s", parts[2], parts[1])
+			}
+
+			// Weeks are calendar-based, so they keep their weekday
+			if strings.HasPrefix(strings.ToLower(parts[2]), "w") {
+				return spec.addWeekdays("week", n)
+			}
+
+			period, err := parseOffset(strconv.Itoa(n) + parts[2])
+			if err != nil {
+				return err
+			}
+			return spec.setPeriod(period)
+		}},
+
+	{regexp.MustCompile(`(?i)^hourly\b`),
+		func(spec *scheduleSpec, parts []string) error {
+			return spec.setPeriod(time.Hour)
+		}},
+
+	// 1: (other)?
+	// 2: (weekday|weekend|week|Mon, Wed and Fri|...)
+	{regexp.MustCompile(`(?i)^(?:every|each)\s+(other\s+)?(weekdays?\b|weekends?\b|week\b|` + weekdaysPattern + `)`),
+		func(spec *scheduleSpec, parts []string) error {
+			interval := 1
+			if parts[1] != "" {
+				interval = 2
+			}
+			return spec.addWeekdays(parts[2], interval)
+		}},
+
+	// 1: (weekdays|weekends|Mondays and Thursdays|...)
+	{regexp.MustCompile(`(?i)^on\s+(weekdays\b|weekends\b|` + weekdaysPattern + `)`),
+		func(spec *scheduleSpec, parts []string) error {
+			return spec.addWeekdays(parts[1], 1)
+		}},
+
+	{regexp.MustCompile(`(?i)^weekly\b`),
+		func(spec *scheduleSpec, parts []string) error {
+			return spec.addWeekdays("week", 1)
+		}},
+
+	// 1: (\d?\d/\d?\d)
+	// 2: (Month name)
+	// 3: (Day of month)
+	{regexp.MustCompile(`(?i)^until\s+(?:(\d?\d/\d?\d)|(` + monthPattern + `)\s+(\d?\d)(?:st|nd|rd|th)?)\b`),
+		func(spec *scheduleSpec, parts []string) error {
+			if spec.until != "" {
+				return errors.New("Your reminder can only have one end date")
+			}
+			spec.until = parts[1]
+			if parts[2] != "" {
+				month := monthsByPrefix[strings.ToLower(parts[2][:3])]
+				spec.until = fmt.Sprintf("%d/%s", month, parts[3])
+			}
+			return nil
+		}},
+
+	// 1: (How often to send it again, if given)
+	{regexp.MustCompile(`(?i)^until\s+(?:i\s+(?:say|reply|text)\s+(?:back\s+)?)?"?done"?(?:\s*,?\s*(?:every|each)\s+(` + offsetPattern + `))?`),
+		func(spec *scheduleSpec, parts []string) error {
+			return spec.setUntilDone(parts[1])
+		}},
+
+	// 1: (How often to send it again, if given)
+	{regexp.MustCompile(`(?i)^(?:and\s+)?nag(?:\s+me)?(?:\s+(?:every|each)\s+(` + offsetPattern + `))?\b`),
+		func(spec *scheduleSpec, parts []string) error {
+			return spec.setUntilDone(parts[1])
+		}},
+
+	// 1: (Phone number)
+	// 2: (How long until they're told, if given)
+	// 3: (What they call the recipient, if given)
+	{regexp.MustCompile(`(?i)^(?:,\s*)?(?:and\s+)?escalate\s+to\s+(\+?[\d(][\d\-.() ]{8,}\d)(?:\s+(?:if\s+not\s+done\s+)?(?:after|within|in)\s+(` + offsetPattern + `))?(?:\s*\bas\s+([a-z][\w']*))?\b`),
+		func(spec *scheduleSpec, parts []string) error {
+			return spec.addEscalation(parts[1], parts[2], parts[3])
+		}},
+
+	// E.g., "3x daily", which could mean 3 times a day or 3 days
+	{regexp.MustCompile(`(?i)^\d+\s*(?:times|x)\s+(?:daily|hourly|weekly|monthly|yearly|(?:a|an|per|each|every)\s+(?:day|hour|week|month|year))\b`),
+		func(spec *scheduleSpec, parts []string) error {
+			return fmt.Errorf("\"%s\" could mean how often or how many"+
+				" times in all; say e.g. \"every 8 hours\", or \"daily for"+
+				" 3 times\" to stop after 3", parts[0])
+		}},
+
+	// 1: (Number of times)
+	{regexp.MustCompile(`(?i)^(?:for\s+)?(\d+)\s*(?:times|x)\b`),
+		func(spec *scheduleSpec, parts []string) error {
+			times, _ := strconv.Atoi(parts[1])
+			if times < 1 {
+				return fmt.Errorf("Invalid number of times: %s", parts[1])
+			}
+			if spec.times != 0 {
+				return errors.New("Your reminder can only say how many times once")
+			}
+			spec.times = times
+			return nil
+		}},
+
+	// Trailing punctuation
+	{regexp.MustCompile(`^[.!]+`),
+		func(spec *scheduleSpec, parts []string) error {
+			return nil
+		}},
+}
+
+// Matches times of day like "18:00", "1800", "6pm", "6:30 P.M.",
+// "noon", and "evening"
+const clockPattern = `\d?\d(?::?\d\d)?\s*[ap]\.?m\b\.?|\d?\d:?\d\d\b|noon\b|midnight\b|morning\b|afternoon\b|evening\b|night\b`
+
+// 0: (Entire time)
+// 1: \d?\d(:?\d\d)?
+// 2: a|p
+var regexTwelveHour = regexp.MustCompile(`(?i)^(\d?\d(?::?\d\d)?)\s*([ap])\.?m\.?$`)
+
+func (spec *scheduleSpec) setClock(s string) error {
+	if spec.hhmm != "" {
+		return errors.New("Your reminder can only have one time of day")
+	}
+	hhmm, err := parseClock(s)
+	if err != nil {
+		return err
+	}
+	spec.hhmm = hhmm
+	return nil
+}
+
+// parseClock converts a time of day matching clockPattern to hh:mm
+// (24-hour time)
+func parseClock(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	switch s {
+	case "noon":
+		return "12:00", nil
+	case "midnight":
+		return "00:00", nil
+	}
+	if hhmm, ok := namedTimes[s]; ok {
+		return hhmm, nil
+	}
+
+	parts := regexTwelveHour.FindStringSubmatch(s)
+	if len(parts) == 0 {
+		hours, mins, err := parseHHMM(s)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%02d:%02d", hours, mins), nil
+	}
+
+	// 12-hour time, e.g. "6pm" or "6:30 PM"
+
+	digits := strings.Replace(parts[1], ":", "", 1)
+	hours, mins := 0, 0
+	if len(digits) <= 2 {
+		hours, _ = strconv.Atoi(digits)
+	} else {
+		hours, _ = strconv.Atoi(digits[:len(digits)-2])
+		mins, _ = strconv.Atoi(digits[len(digits)-2:])
+	}
+
+	if hours < 1 || hours > 12 || mins > 59 {
+		return "", fmt.Errorf("Invalid time %q", s)
+	}
+
+	hours %= 12 // 12am == 00:xx, 12pm == 12:xx
+	if parts[2] == "p" {
+		hours += 12
+	}
+
+	return fmt.Sprintf("%02d:%02d", hours, mins), nil
+}
+
+const monthPattern = `jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sep(?:t|tember)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?`
+
+var monthsByPrefix = map[string]time.Month{
+	"jan": time.January,
+	"feb": time.February,
+	"mar": time.March,
+	"apr": time.April,
+	"may": time.May,
+	"jun": time.June,
+	"jul": time.July,
+	"aug": time.August,
+	"sep": time.September,
+	"oct": time.October,
+	"nov": time.November,
+	"dec": time.December,
+}
+
+const nthPattern = `first|second|third|fourth|fifth|last|1st|2nd|3rd|4th|5th`
+
+var nthByName = map[string]int{
+	"first": 1, "1st": 1,
+	"second": 2, "2nd": 2,
+	"third": 3, "3rd": 3,
+	"fourth": 4, "4th": 4,
+	"fifth": 5, "5th": 5,
+	"last": -1,
+}
+
+func (spec *scheduleSpec) setDay(day string) error {
+	if spec.day != "" {
+		return errors.New("Your reminder can only have one starting day")
+	}
+	spec.day = day
+	return nil
+}
+
+// Matches one or more days of the week, e.g. "Monday", "Tue and Thu",
+// "mon, wed, fri", "Saturdays & Sundays"
+const weekdaysPattern = `(?:` + weekdayPattern + `)(?:\s*(?:,\s*and|,|and|&|/)\s*(?:` + weekdayPattern + `))*`
+
+const weekdayPattern = `(?:sun(?:day)?|mon(?:day)?|tue(?:s|sday)?|wed(?:s|nesday)?|thu(?:r|rs|rsday)?|fri(?:day)?|sat(?:urday)?)s?\b`
+
+var regexWeekday = regexp.MustCompile(`(?i)` + weekdayPattern)
+
+var weekdaysByPrefix = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// addWeekdays makes spec repeat weekly on the days named by s, which
+// matches weekdaysPattern or is "weekday(s)", "weekend(s)", or "week"
+func (spec *scheduleSpec) addWeekdays(s string, interval int) error {
+	if err := spec.setRepeat("weekly"); err != nil {
+		return err
+	}
+	if spec.recurrence == nil {
+		spec.recurrence = &remind.Recurrence{}
+	}
+	if interval > 1 {
+		spec.recurrence.Interval = interval
+	}
+
+	s = strings.ToLower(s)
+
+	var days []time.Weekday
+	switch {
+	case strings.HasPrefix(s, "weekday"):
+		days = remind.Weekdays
+	case strings.HasPrefix(s, "weekend"):
+		days = remind.Weekends
+	case s == "week":
+		// NextRun's weekday
+	default:
+		for _, name := range regexWeekday.FindAllString(s, -1) {
+			days = append(days, weekdaysByPrefix[name[:3]])
+		}
+	}
+
+	for _, wd := range days {
+		if !hasWeekday(spec.recurrence.Weekdays, wd) {
+			spec.recurrence.Weekdays = append(spec.recurrence.Weekdays, wd)
+		}
+	}
+
+	return nil
+}
+
+func hasWeekday(weekdays []time.Weekday, wd time.Weekday) bool {
+	for _, wd2 := range weekdays {
+		if wd == wd2 {
+			return true
+		}
+	}
+	return false
+}
+
+// errNoClause means the text following a would-be description isn't
+// made up entirely of schedule clauses
+var errNoClause = errors.New("Unrecognized text in reminder schedule")
+
+// splitSchedule splits s into a description and the schedule that
+// follows it. Like a lazy regex, the shortest description whose
+// remainder parses as a schedule wins.
+func splitSchedule(s string, loc *time.Location) (string, *scheduleSpec, error) {
+	for i := 1; i < len(s); i++ {
+		startsClause := s[i] == '@' || (s[i-1] == ' ' && s[i] != ' ')
+		if !startsClause {
+			continue
+		}
+
+		description := strings.TrimSpace(s[:i])
+		if description == "" {
+			continue
+		}
+
+		spec, err := parseSchedule(s[i:], loc)
+		if err == errNoClause {
+			continue
+		}
+		if err != nil {
+			// The rest of the message is made of schedule clauses
+			// that don't make sense together
+			return "", nil, err
+		}
+
+		return description, spec, nil
+	}
+
+	return "", nil, errParseReminder
+}
+
+type clauseMatch struct {
+	clause scheduleClause
+	parts  []string
+}
+
+// parseSchedule parses s, which must consist entirely of schedule
+// clauses (else errNoClause is returned), with times of day in loc
+func parseSchedule(s string, loc *time.Location) (*scheduleSpec, error) {
+	var matches []clauseMatch
+
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		matched := false
+
+		for _, clause := range scheduleClauses {
+			loc := clause.re.FindStringSubmatchIndex(s)
+			if loc == nil {
+				continue
+			}
+
+			parts := make([]string, len(loc)/2)
+			for i := range parts {
+				if loc[2*i] >= 0 {
+					parts[i] = s[loc[2*i]:loc[2*i+1]]
+				}
+			}
+			matches = append(matches, clauseMatch{clause, parts})
+
+			s = s[loc[1]:]
+			matched = true
+			break
+		}
+
+		if !matched {
+			return nil, errNoClause
+		}
+	}
+
+	spec := &scheduleSpec{loc: loc}
+
+	for _, match := range matches {
+		if err := match.clause.parse(spec, match.parts); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := spec.finish(); err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+// finish checks that spec's clauses make sense together and fills in
+// what they imply
+func (spec *scheduleSpec) finish() error {
+	// Otherwise "pick up groceries and escalate to ..." couldn't be
+	// acknowledged, so would always escalate
+	if len(spec.escalateTo) > 0 && !spec.untilDone {
+		return errors.New("Only reminders sent \"until done\" can" +
+			" escalate to others")
+	}
+
+	if spec.repeat == "period" && spec.period < remind.MinPeriod {
+		if !spec.untilDone || spec.nagEvery != 0 {
+			return fmt.Errorf("Your reminder can't repeat more often than"+
+				" every %s", remind.MinPeriod)
+		}
+
+		// E.g., "every 10 minutes until done" means sending it again
+		// every 10 minutes until it's done, not forever
+		spec.nagEvery = spec.period
+		spec.repeat, spec.period = "", 0
+		if spec.hhmm == "" && !spec.relative {
+			spec.offset = spec.nagEvery
+			spec.relative = true
+		}
+	}
+
+	onDay := spec.monthDay != 0 || spec.nth != 0
+	if onDay && spec.repeat != "monthly" && spec.repeat != "yearly" {
+		if spec.repeat != "" || spec.starting {
+			return errors.New("Only monthly and yearly reminders can say" +
+				" which day of the month they repeat on")
+		}
+		if spec.day != "" || spec.relative {
+			return errors.New("Your reminder can only have one day")
+		}
+
+		// E.g., "on the 5th" without "every month" is the next 5th
+		spec.onDay = &remind.Recurrence{
+			Freq:     remind.Monthly,
+			MonthDay: spec.monthDay,
+			Nth:      spec.nth,
+		}
+		if spec.nth != 0 {
+			spec.onDay.Weekdays = []time.Weekday{spec.nthWeekday}
+		}
+	}
+
+	switch spec.repeat {
+	case "monthly", "yearly":
+		spec.recurrence = &remind.Recurrence{
+			Freq:     remind.Frequency(spec.repeat),
+			Interval: spec.interval,
+			MonthDay: spec.monthDay,
+			Nth:      spec.nth,
+		}
+		if spec.nth != 0 {
+			spec.recurrence.Weekdays = []time.Weekday{spec.nthWeekday}
+		}
+	}
+
+	if spec.repeat == "cron" {
+		if spec.hhmm != "" || spec.relative {
+			return errors.New("Cron reminders get their times from the" +
+				" cron expression")
+		}
+		return nil
+	}
+
+	if spec.hhmm == "" && !spec.relative {
+		switch {
+		case spec.repeat == "period" && spec.period < 24*time.Hour &&
+			spec.day == "":
+			// E.g., "every 2 hours" starts 2 hours from now
+			spec.offset = spec.period
+			spec.relative = true
+		case spec.day == "tonight":
+			spec.hhmm = namedTimes["night"]
+		case spec.repeat != "":
+			spec.hhmm = namedTimes["morning"]
+		default:
+			return errNoClause
+		}
+	}
+
+	return nil
+}
+
+func (spec *scheduleSpec) nextRun() (time.Time, error) {
+	if !spec.relative {
+		return parseTime(spec.hhmm, spec.day, spec.loc)
+	}
+
+	if spec.day != "" || spec.starting {
+		return time.Time{}, errors.New("Your reminder can't say both \"in\"" +
+			" and which day to start on")
+	}
+
+	now := remind.Now().In(spec.loc)
+	nextRun := now.Add(spec.offset)
+
+	if spec.hhmm != "" {
+		// E.g., "in 2 days @ 18:00"
+		hours, mins, err := parseHHMM(spec.hhmm)
+		if err != nil {
+			return time.Time{}, err
+		}
+		year, month, day := nextRun.Date()
+		nextRun = remind.WallClock(year, month, day, hours, mins, 0, spec.loc)
+		if nextRun.Before(now) {
+			return time.Time{}, fmt.Errorf("%s has already passed",
+				nextRun.Format("Jan 2 15:04"))
+		}
+	}
+
+	return nextRun, nil
+}
+
+// cronStart returns when a cron reminder should start firing: now, or
+// the start of the day it was told to start on
+func (spec *scheduleSpec) cronStart() (time.Time, error) {
+	now := remind.Now().In(spec.loc)
+
+	switch spec.day {
+	case "", "today", "tonight":
+		return now, nil
+	}
+
+	start, err := parseTime("00:00", spec.day, spec.loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// So that cron expressions can fire at midnight
+	return start.Add(-time.Minute), nil
+}
+
+// parseUntil returns the end of the day mm/dd, the first one on or
+// after start, in start's location
+func parseUntil(mmdd string, start time.Time) (time.Time, error) {
+	monthDay := strings.SplitN(mmdd, "/", 2)
+	month, _ := strconv.Atoi(monthDay[0])
+	day, _ := strconv.Atoi(monthDay[1])
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}, fmt.Errorf("Invalid end date %q", mmdd)
+	}
+
+	until := remind.WallClock(start.Year(), time.Month(month), day+1, 0, 0, 0,
+		start.Location()).Add(-time.Second)
+	if until.Before(start) {
+		until = until.AddDate(1, 0, 0)
+	}
+
+	return until, nil
+}
+
+// parseOffset parses relative offsets like "45 minutes", "1h30m", and
+// "2 days and 3 hours"
+func parseOffset(s string) (time.Duration, error) {
+	var offset time.Duration
+
+	for _, parts := range regexOffsetUnit.FindAllStringSubmatch(s, -1) {
+		n := 1
+		if parts[1][0] != 'a' && parts[1][0] != 'A' {
+			n, _ = strconv.Atoi(parts[1])
+		}
+
+		var unit time.Duration
+		switch strings.ToLower(parts[2])[0] {
+		case 'w':
+			unit = 7 * 24 * time.Hour
+		case 'd':
+			unit = 24 * time.Hour
+		case 'h':
+			unit = time.Hour
+		case 'm':
+			unit = time.Minute
+		}
+
+		offset += time.Duration(n) * unit
+	}
+
+	if offset <= 0 {
+		return 0, fmt.Errorf("Invalid time from now: %q", s)
+	}
+
+	return offset, nil
+}
+
+// parseHHMM parses the hours and minutes from hh:mm or hhmm
+func parseHHMM(hhmm string) (hours, mins int, err error) {
+	hhmm = strings.Replace(hhmm, ":", "", 1)
+	if len(hhmm) < 3 {
+		return 0, 0, fmt.Errorf("Invalid time %q", hhmm)
+	}
+
+	hours, _ = strconv.Atoi(hhmm[:len(hhmm)-2])
+	mins, _ = strconv.Atoi(hhmm[len(hhmm)-2:])
+
+	if hours > 23 || mins > 59 {
+		return 0, 0, fmt.Errorf("Invalid time %q; must be between 00:00"+
+			" and 23:59", hhmm[:len(hhmm)-2]+":"+hhmm[len(hhmm)-2:])
+	}
+
+	return hours, mins, nil
+}
+
+// parseTime returns the next hh:mm in loc on day, which is empty,
+// today, tonight, tomorrow, or mm/dd
+func parseTime(hhmm, day string, loc *time.Location) (time.Time, error) {
+	hours, mins, err := parseHHMM(hhmm)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	now := remind.Now().In(loc)
+	year, month, dayNum := now.Date()
+
+	if day == "" || day == "today" || day == "tonight" || day == "tomorrow" {
+		nextRun := remind.WallClock(year, month, dayNum, hours, mins, 0, loc)
 
 		if nextRun.Before(now) || day == "tomorrow" {
 			// Tomorrow
-			nextRun = nextRun.Add(24 * time.Hour)
+			nextRun = remind.WallClock(year, month, dayNum+1, hours, mins, 0, loc)
 		}
 
 		return nextRun, nil
@@ -264,16 +1692,16 @@ func parseTime(hhmm string, day string) (time.Time, error) {
 	// Guaranteed: day is of the form `\d?\d/\d?\d`
 
 	monthDay := strings.SplitN(day, "/", 2)
-	month, _ := strconv.Atoi(monthDay[0])
-	dayNum, _ := strconv.Atoi(monthDay[1])
+	monthNum, _ := strconv.Atoi(monthDay[0])
+	dayNum, _ = strconv.Atoi(monthDay[1])
 
-	nextRun := time.Date(now.Year(), time.Month(month), dayNum,
-		hours, mins, 0, 0, remind.LosAngeles)
+	nextRun := remind.WallClock(year, time.Month(monthNum), dayNum,
+		hours, mins, 0, loc)
 
 	if nextRun.Before(now) {
 		// Next year
-		nextRun = time.Date(now.Year()+1, time.Month(month), dayNum,
-			hours, mins, 0, 0, remind.LosAngeles)
+		nextRun = remind.WallClock(year+1, time.Month(monthNum), dayNum,
+			hours, mins, 0, loc)
 	}
 
 	return nextRun, nil
@@ -4,15 +4,46 @@
 package main
 
 import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/boltdb/bolt"
 	"github.com/elimisteve/do_reminder/remind"
+	"github.com/elimisteve/do_reminder/twilhelp"
 	"github.com/stretchr/testify/assert"
 )
 
+// todayOrTomorrow returns the next time it will be hours:mins
+func todayOrTomorrow(hours, mins int) time.Time {
+	now := remind.Now()
+	nowYear, nowMonth, nowDay := now.Date()
+	next := time.Date(nowYear, nowMonth, nowDay, hours, mins, 0, 0,
+		remind.LosAngeles)
+	if next.Before(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
 func TestReminderRegex(t *testing.T) {
-	nowYear, nowMonth, nowDay := remind.Now().Date()
+	// In the morning, so 18:00 and 23:59 are still to come today
+	clk := remind.NewFakeClock(time.Date(2026, 6, 1, 8, 0, 0, 0,
+		remind.LosAngeles))
+	prev := remind.SetClock(clk)
+	defer remind.SetClock(prev)
+
+	now := remind.Now()
+	nowYear, nowMonth, nowDay := now.Date()
 
 	tests := []struct {
 		body string
@@ -76,10 +107,24 @@ func TestReminderRegex(t *testing.T) {
 				Period: 24 * time.Hour,
 			},
 		},
+		{
+			"Remind me to check the oven in 45 minutes",
+			remind.Reminder{
+				Description: "Check the oven",
+				NextRun:     now.Add(45 * time.Minute),
+			},
+		},
+		{
+			"Remind me to take a break in an hour and 15 mins",
+			remind.Reminder{
+				Description: "Take a break",
+				NextRun:     now.Add(75 * time.Minute),
+			},
+		},
 	}
 
 	for _, test := range tests {
-		r, err := parseReminder("", test.body)
+		r, err := parseReminder(&remind.User{}, test.body)
 		if err != nil {
 			t.Errorf("Error parsing `%s`: %v", test.body, err)
 			continue
@@ -90,11 +135,689 @@ func TestReminderRegex(t *testing.T) {
 		assert.Equal(t, r.Period, test.rem.Period, "Period is wrong")
 		assert.Equal(t, r.PlusMinus, time.Duration(0), "PlusMinus is wrong")
 
-		r, _ = parseReminder("", test.body+" daily")
+		r, _ = parseReminder(&remind.User{}, test.body+" daily")
 		assert.Equal(t, r.Period, 24*time.Hour, "Period is wrong (daily)")
 	}
 }
 
+func TestNamedTimeReminder(t *testing.T) {
+	// Before 12/31, so "on 12/31" is this year's
+	clk := remind.NewFakeClock(time.Date(2026, 6, 1, 8, 0, 0, 0,
+		remind.LosAngeles))
+	prev := remind.SetClock(clk)
+	defer remind.SetClock(prev)
+
+	nowYear, nowMonth, nowDay := remind.Now().Date()
+
+	tests := []struct {
+		body        string
+		description string
+		nextRun     time.Time
+	}{
+		{
+			"Remind me to eat dinner at 6pm",
+			"Eat dinner",
+			todayOrTomorrow(18, 00),
+		},
+		{
+			"Remind me to eat dinner at 6:30 PM",
+			"Eat dinner",
+			todayOrTomorrow(18, 30),
+		},
+		{
+			"Remind me to go to bed @ 11:45p.m. tomorrow",
+			"Go to bed",
+			time.Date(nowYear, nowMonth, nowDay+1, 23, 45, 0, 0,
+				remind.LosAngeles),
+		},
+		{
+			"Remind me to have lunch at noon",
+			"Have lunch",
+			todayOrTomorrow(12, 00),
+		},
+		{
+			"Remind me to lock up at 12am",
+			"Lock up",
+			todayOrTomorrow(0, 00),
+		},
+		{
+			"Remind me to run the backup at midnight on 12/31",
+			"Run the backup",
+			time.Date(nowYear, 12, 31, 0, 0, 0, 0, remind.LosAngeles),
+		},
+		{
+			"Remind me to stretch tomorrow morning",
+			"Stretch",
+			time.Date(nowYear, nowMonth, nowDay+1, 9, 0, 0, 0,
+				remind.LosAngeles),
+		},
+		{
+			"Remind me to read in the evening",
+			"Read",
+			todayOrTomorrow(18, 00),
+		},
+	}
+
+	for _, test := range tests {
+		r, err := parseReminder(&remind.User{}, test.body)
+		if err != nil {
+			t.Errorf("Error parsing `%s`: %v", test.body, err)
+			continue
+		}
+
+		assert.Equal(t, test.description, r.Description, "Description is wrong")
+		assert.Equal(t, test.nextRun, r.NextRun, "NextRun is wrong for `%s`",
+			test.body)
+	}
+
+	// Once it's passed, it's next year's
+	clk.Advance(time.Date(2026, 12, 31, 12, 0, 0, 0, remind.LosAngeles).Sub(
+		remind.Now()))
+	r, err := parseReminder(&remind.User{}, "Remind me to run the backup at"+
+		" midnight on 12/31")
+	if assert.NoError(t, err) {
+		assert.Equal(t, time.Date(2027, 12, 31, 0, 0, 0, 0, remind.LosAngeles),
+			r.NextRun)
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		clock string
+		hhmm  string
+	}{
+		{"18:00", "18:00"},
+		{"1800", "18:00"},
+		{"6pm", "18:00"},
+		{"6 pm", "18:00"},
+		{"6:30 PM", "18:30"},
+		{"630pm", "18:30"},
+		{"7 a.m.", "07:00"},
+		{"12am", "00:00"},
+		{"12:15pm", "12:15"},
+		{"noon", "12:00"},
+		{"Midnight", "00:00"},
+		{"morning", namedTimes["morning"]},
+		{"evening", namedTimes["evening"]},
+	}
+
+	for _, test := range tests {
+		hhmm, err := parseClock(test.clock)
+		if err != nil {
+			t.Errorf("Error parsing `%s`: %v", test.clock, err)
+			continue
+		}
+		assert.Equal(t, test.hhmm, hhmm, "Wrong time for `%s`", test.clock)
+	}
+
+	for _, bad := range []string{"13pm", "0am", "6:75pm", "24:00"} {
+		_, err := parseClock(bad)
+		assert.Error(t, err, "Parsing `%s` should fail", bad)
+	}
+}
+
+func TestWeeklyReminder(t *testing.T) {
+	tests := []struct {
+		body       string
+		weekdays   []time.Weekday
+		interval   int
+		repetition string
+	}{
+		{
+			"Remind me to take out the recycling every Monday at 7pm",
+			[]time.Weekday{time.Monday},
+			0,
+			"every Mon",
+		},
+		{
+			"Remind me to stand up @ 10:30 on weekdays",
+			remind.Weekdays,
+			0,
+			"every weekday",
+		},
+		{
+			"Remind me to go to the gym every Tue and Thu at 6am",
+			[]time.Weekday{time.Tuesday, time.Thursday},
+			0,
+			"every Tue, Thu",
+		},
+		{
+			"Remind me to mow the lawn at 9am every other Saturday",
+			[]time.Weekday{time.Saturday},
+			2,
+			"every other Sat",
+		},
+		{
+			"Remind me to call Grandma at 5pm weekly",
+			nil,
+			0,
+			"every week",
+		},
+		{
+			"Remind me to sleep in on Saturdays and Sundays at 11:00",
+			remind.Weekends,
+			0,
+			"every weekend",
+		},
+	}
+
+	for _, test := range tests {
+		r, err := parseReminder(&remind.User{}, test.body)
+		if err != nil {
+			t.Errorf("Error parsing `%s`: %v", test.body, err)
+			continue
+		}
+		if !assert.NotNil(t, r.Recurrence, "Recurrence missing for `%s`",
+			test.body) {
+			continue
+		}
+
+		assert.Equal(t, test.weekdays, r.Recurrence.Weekdays,
+			"Weekdays are wrong for `%s`", test.body)
+		assert.Equal(t, test.interval, r.Recurrence.Interval,
+			"Interval is wrong for `%s`", test.body)
+		assert.Equal(t, test.repetition, r.Repetition())
+		assert.Equal(t, time.Duration(0), r.Period, "Period is wrong")
+		assert.True(t, r.Recurrence.Includes(r.NextRun),
+			"NextRun %s isn't %s", r.NextRun, test.repetition)
+		assert.True(t, r.NextRun.After(remind.Now()), "NextRun is in the past")
+	}
+}
+
+func TestCalendarReminder(t *testing.T) {
+	tests := []struct {
+		body        string
+		description string
+		rec         remind.Recurrence
+		repetition  string
+	}{
+		{
+			"Remind me to pay rent on the 1st of every month",
+			"Pay rent",
+			remind.Recurrence{Freq: remind.Monthly, MonthDay: 1},
+			"every month on the 1st",
+		},
+		{
+			"Remind me to submit my timesheet on the last Friday of the month at 3pm",
+			"Submit my timesheet",
+			remind.Recurrence{Freq: remind.Monthly, Nth: -1,
+				Weekdays: []time.Weekday{time.Friday}},
+			"every month on the last Fri",
+		},
+		{
+			"Remind me to go to book club every 2nd Tuesday at 7pm",
+			"Go to book club",
+			remind.Recurrence{Freq: remind.Monthly, Nth: 2,
+				Weekdays: []time.Weekday{time.Tuesday}},
+			"every month on the 2nd Tue",
+		},
+		{
+			"Remind me to pay the credit card on the last day of every other month",
+			"Pay the credit card",
+			remind.Recurrence{Freq: remind.Monthly, MonthDay: -1, Interval: 2},
+			"every other month on the last day",
+		},
+		{
+			"Remind me to file taxes every March 15",
+			"File taxes",
+			remind.Recurrence{Freq: remind.Yearly, Month: time.March,
+				MonthDay: 15},
+			"every year on Mar 15th",
+		},
+		{
+			"Remind me to renew the domain @ 10:00 on 4/2 every year",
+			"Renew the domain",
+			remind.Recurrence{Freq: remind.Yearly, Month: time.April,
+				MonthDay: 2},
+			"every year on Apr 2nd",
+		},
+	}
+
+	for _, test := range tests {
+		r, err := parseReminder(&remind.User{}, test.body)
+		if err != nil {
+			t.Errorf("Error parsing `%s`: %v", test.body, err)
+			continue
+		}
+
+		assert.Equal(t, test.description, r.Description, "Description is wrong")
+		if !assert.NotNil(t, r.Recurrence, "Recurrence missing for `%s`",
+			test.body) {
+			continue
+		}
+		assert.Equal(t, test.rec, *r.Recurrence, "Recurrence is wrong for `%s`",
+			test.body)
+		assert.Equal(t, test.repetition, r.Repetition())
+		assert.True(t, r.Recurrence.Includes(r.NextRun),
+			"NextRun %s isn't %s", r.NextRun, test.repetition)
+		assert.True(t, r.NextRun.After(remind.Now()), "NextRun is in the past")
+	}
+
+	// No time given; defaults to the morning
+	r, err := parseReminder(&remind.User{}, "Remind me to pay rent on the 1st of every month")
+	if assert.NoError(t, err) {
+		hhmm, _ := parseClock("morning")
+		assert.Equal(t, hhmm, r.NextRun.Format("15:04"))
+		assert.Equal(t, 1, r.NextRun.Day())
+	}
+
+	// A date without "every" is a one-off
+	r, err = parseReminder(&remind.User{}, "Remind me to call Dad on March 15 at noon")
+	if assert.NoError(t, err) {
+		assert.Nil(t, r.Recurrence)
+		assert.Equal(t, time.March, r.NextRun.Month())
+		assert.Equal(t, 15, r.NextRun.Day())
+	}
+}
+
+func TestDayOfMonthReminder(t *testing.T) {
+	// Wednesday
+	clk := remind.NewFakeClock(time.Date(2026, 6, 10, 8, 0, 0, 0,
+		remind.LosAngeles))
+	prev := remind.SetClock(clk)
+	defer remind.SetClock(prev)
+
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2026, month, day, hour, 0, 0, 0, remind.LosAngeles)
+	}
+
+	// Without "every month", the next one
+	tests := []struct {
+		body    string
+		nextRun time.Time
+	}{
+		{"Remind me to pay rent on the 5th at 9am", at(7, 5, 9)},
+		{"Remind me to pay rent on the 10th at 9am", at(6, 10, 9)},
+		{"Remind me to pay rent on the 10th at 7am", at(7, 10, 7)},
+		{"Remind me to pay rent on the last day at 9am", at(6, 30, 9)},
+		{"Remind me to submit my timesheet on the last Friday at 3pm",
+			at(6, 26, 15)},
+	}
+	for _, test := range tests {
+		r, err := parseReminder(&remind.User{}, test.body)
+		if !assert.NoError(t, err, test.body) {
+			continue
+		}
+		assert.NotContains(t, r.Description, " on the ", test.body)
+		assert.Nil(t, r.Recurrence, test.body)
+		assert.False(t, r.Repeats(), test.body)
+		assert.Equal(t, test.nextRun, r.NextRun, test.body)
+	}
+
+	bad := []string{
+		"Remind me to pay rent on the 5th daily at 9am",
+		"Remind me to pay rent tomorrow on the 5th at 9am",
+		"Remind me to pay rent on the 5th in 2 hours",
+	}
+	for _, body := range bad {
+		_, err := parseReminder(&remind.User{}, body)
+		assert.Error(t, err, body)
+	}
+}
+
+func TestRRuleReminder(t *testing.T) {
+	r, err := parseReminder(&remind.User{},
+		"Remind me to water the garden at 7am FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "Water the garden", r.Description)
+	assert.Equal(t, "FREQ=WEEKLY;COUNT=10;BYDAY=MO,WE", r.RRule)
+	assert.Equal(t, time.Duration(0), r.Period)
+	assert.Nil(t, r.Recurrence)
+	assert.Equal(t, "07:00", r.NextRun.Format("15:04"))
+	assert.Contains(t, []time.Weekday{time.Monday, time.Wednesday},
+		r.NextRun.Weekday())
+	assert.True(t, r.NextRun.After(remind.Now()), "NextRun is in the past")
+
+	r, err = parseReminder(&remind.User{}, "Remind me to pay rent RRULE:FREQ=MONTHLY;BYMONTHDAY=1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Pay rent", r.Description)
+		assert.Equal(t, 1, r.NextRun.Day())
+	}
+
+	_, err = parseReminder(&remind.User{}, "Remind me to panic FREQ=MINUTELY")
+	assert.Error(t, err)
+
+	_, err = parseReminder(&remind.User{}, "Remind me to panic FREQ=DAILY;BYMINUTE=0,5")
+	assert.Error(t, err)
+}
+
+func TestCronReminder(t *testing.T) {
+	r, err := parseReminder(&remind.User{}, "Remind me to stand up cron 0 9 * * 1-5")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "Stand up", r.Description)
+	assert.Equal(t, "0 9 * * 1-5", r.Cron)
+	assert.Equal(t, time.Duration(0), r.Period)
+	assert.Equal(t, "09:00", r.NextRun.Format("15:04"))
+	assert.Contains(t, remind.Weekdays, r.NextRun.Weekday())
+	assert.True(t, r.NextRun.After(remind.Now()), "NextRun is in the past")
+
+	r, err = parseReminder(&remind.User{}, "Remind me to celebrate cron 0 0 1 1 * starting 12/31")
+	if assert.NoError(t, err) {
+		assert.Equal(t, time.January, r.NextRun.Month())
+		assert.Equal(t, 1, r.NextRun.Day())
+	}
+
+	bad := []string{
+		"Remind me to stand up cron 0 9 * *",
+		"Remind me to stand up at 9am cron 0 9 * * 1-5",
+		"Remind me to stand up cron 0 9 * * 1-5 daily",
+		"Remind me to breathe cron * * * * *",
+	}
+	for _, body := range bad {
+		_, err := parseReminder(&remind.User{}, body)
+		assert.Error(t, err, "Parsing `%s` should fail", body)
+	}
+}
+
+func TestLimitedReminder(t *testing.T) {
+	r, err := parseReminder(&remind.User{}, "Remind me to take my antibiotics at 8am daily 10 times")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Take my antibiotics", r.Description)
+		assert.Equal(t, 24*time.Hour, r.Period)
+		assert.Equal(t, 10, r.MaxRuns)
+		assert.True(t, r.Until.IsZero())
+		assert.Equal(t, "every 24h0m0s, 10 times", r.Repetition())
+	}
+
+	r, err = parseReminder(&remind.User{}, "Remind me to water the tree every Monday at 7am until 12/31")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Water the tree", r.Description)
+		assert.Equal(t, 0, r.MaxRuns)
+		assert.Equal(t, time.December, r.Until.Month())
+		assert.Equal(t, 31, r.Until.Day())
+		assert.Equal(t, "23:59:59", r.Until.Format("15:04:05"))
+		assert.True(t, r.Until.After(r.NextRun))
+	}
+
+	r, err = parseReminder(&remind.User{}, "Remind me to stretch daily until Jan 5th")
+	if assert.NoError(t, err) {
+		assert.Equal(t, time.January, r.Until.Month())
+		assert.Equal(t, 5, r.Until.Day())
+	}
+
+	_, err = parseReminder(&remind.User{}, "Remind me to buy milk at 5pm 3 times")
+	assert.Error(t, err, "One-off reminders can't have a count")
+
+	_, err = parseReminder(&remind.User{}, "Remind me to buy milk at 5pm daily 0 times")
+	assert.Error(t, err)
+
+	// "3x daily" could mean 3 times a day, so "for" says it's 3 days
+	for _, body := range []string{
+		"Remind me to take my pills 3x daily",
+		"Remind me to take my pills at 8am 3 times a day",
+		"Remind me to stretch 2x per hour",
+	} {
+		_, err = parseReminder(&remind.User{}, body)
+		assert.Error(t, err, body)
+	}
+	r, err = parseReminder(&remind.User{}, "Remind me to take my pills at 8am for 3x daily")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Take my pills", r.Description)
+		assert.Equal(t, 24*time.Hour, r.Period)
+		assert.Equal(t, 3, r.MaxRuns)
+	}
+}
+
+func TestPeriodReminder(t *testing.T) {
+	now := remind.Now()
+
+	r, err := parseReminder(&remind.User{}, "Remind me to drink water every 2 hours")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Drink water", r.Description)
+		assert.Equal(t, 2*time.Hour, r.Period)
+		assert.WithinDuration(t, now.Add(2*time.Hour), r.NextRun, 5*time.Second)
+		assert.Equal(t, "every 2h0m0s", r.Repetition())
+	}
+
+	r, err = parseReminder(&remind.User{}, "Remind me to stand up every 90 minutes starting at 9am")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Stand up", r.Description)
+		assert.Equal(t, 90*time.Minute, r.Period)
+		assert.Equal(t, todayOrTomorrow(9, 0), r.NextRun)
+	}
+
+	r, err = parseReminder(&remind.User{}, "Remind me to water the plants every 3 days")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 72*time.Hour, r.Period)
+		assert.Equal(t, todayOrTomorrow(9, 0), r.NextRun)
+	}
+
+	r, err = parseReminder(&remind.User{}, "Remind me to change the sheets every other day @ 20:00")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 48*time.Hour, r.Period)
+		assert.Equal(t, todayOrTomorrow(20, 0), r.NextRun)
+	}
+
+	r, err = parseReminder(&remind.User{}, "Remind me to mow the lawn every 2 weeks at 10am")
+	if assert.NoError(t, err) {
+		assert.Equal(t, time.Duration(0), r.Period)
+		if assert.NotNil(t, r.Recurrence) {
+			assert.Equal(t, 2, r.Recurrence.Interval)
+		}
+	}
+
+	r, err = parseReminder(&remind.User{}, "Remind me to check the oven hourly")
+	if assert.NoError(t, err) {
+		assert.Equal(t, time.Hour, r.Period)
+	}
+
+	_, err = parseReminder(&remind.User{}, "Remind me to breathe every 5 minutes")
+	assert.Error(t, err, "Periods under remind.MinPeriod would flood")
+
+	_, err = parseReminder(&remind.User{}, "Remind me to breathe every minute")
+	assert.Error(t, err)
+
+	_, err = parseReminder(&remind.User{}, "Remind me to stretch every 2 hours daily")
+	assert.Error(t, err)
+
+	_, err = parseReminder(&remind.User{}, "Remind me to stretch around 9am every 90 minutes")
+	assert.Error(t, err, "A +/- 60m window overlaps the next run")
+}
+
+func TestAroundWindowReminder(t *testing.T) {
+	user := &remind.User{Phone: "+15555550100"}
+
+	r, err := parseReminder(user, "Remind me to call mom around 15:00")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "+15555550100", r.Recipient)
+		assert.Equal(t, remind.DefaultAroundWindow, r.PlusMinus)
+	}
+
+	r, err = parseReminder(user, "Remind me to call mom around 15:00 give or take 20 minutes")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Call mom", r.Description)
+		assert.Equal(t, todayOrTomorrow(15, 0), r.NextRun)
+		assert.Equal(t, 20*time.Minute, r.PlusMinus)
+	}
+
+	r, err = parseReminder(user, "Remind me to call mom around 15:00 ±10m")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 10*time.Minute, r.PlusMinus)
+	}
+
+	r, err = parseReminder(user, "Remind me to call mom at 3pm +/- 5 mins daily")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 5*time.Minute, r.PlusMinus)
+		assert.Equal(t, 24*time.Hour, r.Period)
+	}
+
+	r, err = parseReminder(user, "Remind me to stretch every 90 minutes give or take 10 minutes")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 10*time.Minute, r.PlusMinus)
+	}
+
+	user.AroundWindow = 30 * time.Minute
+	r, err = parseReminder(user, "Remind me to call mom around 15:00")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 30*time.Minute, r.PlusMinus)
+	}
+
+	r, err = parseReminder(user, "Remind me to call mom at 15:00")
+	if assert.NoError(t, err) {
+		assert.Equal(t, time.Duration(0), r.PlusMinus)
+	}
+
+	_, err = parseReminder(user, "Remind me to call mom around 15:00 give or take 2 days")
+	assert.Error(t, err)
+
+	_, err = parseReminder(user, "Remind me to call mom around 15:00 ±10m ±20m")
+	assert.Error(t, err)
+}
+
+func TestAroundWindowRegex(t *testing.T) {
+	tests := []struct {
+		body   string
+		window string
+	}{
+		{"Set my around window to 20 minutes", "20 minutes"},
+		{"window 45m", "45m"},
+		{"default \"around\" window = 1 hour.", "1 hour"},
+		{"Remind me to open the window at 5pm", ""},
+	}
+
+	for _, test := range tests {
+		parts := regexAroundWindow.FindStringSubmatch(test.body)
+		if test.window == "" {
+			assert.Empty(t, parts, test.body)
+			continue
+		}
+		if assert.Len(t, parts, 2, test.body) {
+			assert.Equal(t, test.window, parts[1])
+		}
+	}
+}
+
+func TestTimezoneReminder(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	user := &remind.User{Phone: "+15555550100", Timezone: "Europe/Berlin"}
+
+	r, err := parseReminder(user, "Remind me to call Oma at 18:00 tomorrow")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Europe/Berlin", r.Timezone)
+
+		local := r.NextRun.In(berlin)
+		tomorrow := remind.Now().In(berlin).AddDate(0, 0, 1)
+		assert.Equal(t, "18:00", local.Format("15:04"))
+		assert.Equal(t, tomorrow.Day(), local.Day())
+	}
+
+	r, err = parseReminder(user, "Remind me to stretch at 7am every weekday")
+	if assert.NoError(t, err) {
+		local := r.NextRun.In(berlin)
+		assert.Equal(t, "07:00", local.Format("15:04"))
+		assert.NotEqual(t, time.Saturday, local.Weekday())
+		assert.NotEqual(t, time.Sunday, local.Weekday())
+	}
+
+	// Inferred from the country code
+	r, err = parseReminder(&remind.User{Phone: "+819012345678"},
+		"Remind me to buy natto at 8am")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Asia/Tokyo", r.Timezone)
+		tokyo, _ := time.LoadLocation("Asia/Tokyo")
+		assert.Equal(t, "08:00", r.NextRun.In(tokyo).Format("15:04"))
+	}
+}
+
+func TestTimezoneRegex(t *testing.T) {
+	tests := []struct {
+		body string
+		zone string
+	}{
+		{"timezone Europe/Berlin", "Europe/Berlin"},
+		{"Set my time zone to America/New_York.", "America/New_York"},
+		{"tz utc", "utc"},
+		{"Remind me to change the timezone at 5pm", ""},
+	}
+
+	for _, test := range tests {
+		parts := regexTimezone.FindStringSubmatch(test.body)
+		if test.zone == "" {
+			assert.Empty(t, parts, test.body)
+			continue
+		}
+		if assert.Len(t, parts, 2, test.body) {
+			assert.Equal(t, test.zone, parts[1])
+		}
+	}
+}
+
+func TestRelativeReminder(t *testing.T) {
+	tests := []struct {
+		body        string
+		description string
+		offset      time.Duration
+		period      time.Duration
+		plusMinus   time.Duration
+	}{
+		{
+			"Remind me to check the oven in 45 minutes",
+			"Check the oven",
+			45 * time.Minute,
+			0,
+			0,
+		},
+		{
+			"Remind me to stretch in 1h30m",
+			"Stretch",
+			90 * time.Minute,
+			0,
+			0,
+		},
+		{
+			"remind me to water the plants in 2 days daily",
+			"Water the plants",
+			48 * time.Hour,
+			24 * time.Hour,
+			0,
+		},
+		{
+			"Remind me to call in to the meeting around in 2 hours",
+			"Call in to the meeting",
+			2 * time.Hour,
+			0,
+			60 * time.Minute,
+		},
+		{
+			"Remind me to take a break in an hour and 15 mins",
+			"Take a break",
+			75 * time.Minute,
+			0,
+			0,
+		},
+	}
+
+	for _, test := range tests {
+		r, err := parseReminder(&remind.User{}, test.body)
+		if err != nil {
+			t.Errorf("Error parsing `%s`: %v", test.body, err)
+			continue
+		}
+
+		assert.Equal(t, test.description, r.Description, "Description is wrong")
+		assert.WithinDuration(t, remind.Now().Add(test.offset), r.NextRun,
+			time.Second, "NextRun is wrong")
+		assert.Equal(t, test.period, r.Period, "Period is wrong")
+		assert.Equal(t, test.plusMinus, r.PlusMinus, "PlusMinus is wrong")
+	}
+
+	bad := []string{
+		"Remind me to jump in 2 hours on 1/1",
+		"Remind me to jump in 3 fortnights",
+		"Remind me to jump at 25:00",
+	}
+	for _, body := range bad {
+		_, err := parseReminder(&remind.User{}, body)
+		assert.Error(t, err, "Parsing `%s` should fail", body)
+	}
+}
+
 func TestCancelRegex(t *testing.T) {
 	tests := []struct {
 		msg string
@@ -127,3 +850,505 @@ func TestCancelRegex(t *testing.T) {
 		assert.Equal(t, test.id, parts[1])
 	}
 }
+
+// newTestServer points the global scheduler at a fresh DB, which it
+// returns
+func newTestServer(t *testing.T) *bolt.DB {
+	dir, err := os.MkdirTemp("", "do_reminder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	scheduler = remind.NewScheduler(db, twilhelp.NewFakeSender(), 1)
+
+	return db
+}
+
+// textIn handles body as though Twilio had POSTed it to /sms from
+// from, returning the replies
+func textIn(t *testing.T, db *bolt.DB, from, body string) []string {
+	form := url.Values{"From": {from}, "Body": {body}}
+	req := httptest.NewRequest("POST", "/sms", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp := incomingSMS(db, req, log.New(io.Discard, "", 0))
+
+	var twiml struct {
+		Messages []string `xml:"Message"`
+	}
+	if err := xml.Unmarshal([]byte(resp), &twiml); err != nil {
+		t.Fatalf("Error parsing TwiML %q: %v", resp, err)
+	}
+	return twiml.Messages
+}
+
+func TestIncomingSMS(t *testing.T) {
+	db := newTestServer(t)
+	from := "+15555550100"
+
+	replies := textIn(t, db, from, "Remind me to buy milk at 6pm tomorrow")
+	if assert.Len(t, replies, 1) {
+		assert.Contains(t, replies[0], "Reminder 1 successfully scheduled")
+	}
+	assert.Equal(t, 1, scheduler.Len())
+
+	replies = textIn(t, db, from, "Stop 1")
+	assert.Equal(t, []string{"Reminder 1 stopped. Have an epic day!"}, replies)
+	assert.Equal(t, 0, scheduler.Len())
+
+	replies = textIn(t, db, from, "Set my timezone to Europe/Berlin")
+	if assert.Len(t, replies, 1) {
+		assert.Contains(t, replies[0], "Your timezone is now Europe/Berlin")
+	}
+
+	replies = textIn(t, db, from, "Set my around window to 20 minutes")
+	if assert.Len(t, replies, 1) {
+		assert.Contains(t, replies[0], "up to 20m0s before or after")
+	}
+
+	user, err := remind.GetUser(db, from)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Europe/Berlin", user.Timezone)
+		assert.Equal(t, 20*time.Minute, user.AroundWindow)
+	}
+
+	// Not understood
+	replies = textIn(t, db, from, "Hello?")
+	assert.Equal(t, []string{errParseReminder.Error()}, replies)
+}
+
+func TestTwilioResponse(t *testing.T) {
+	assert.Equal(t, xml.Header+"<Response>\n</Response>", twilioResponse())
+	assert.Equal(t, xml.Header+"<Response>\n"+
+		"<Message>Reminder 1: Buy milk &amp; eggs &lt;2%&gt;</Message>\n"+
+		"<Message>Bye</Message>\n</Response>",
+		twilioResponse("Reminder 1: Buy milk & eggs <2%>", "Bye"))
+}
+
+func TestListReminders(t *testing.T) {
+	// So New York is on daylight time
+	clk := remind.NewFakeClock(time.Date(2026, 6, 1, 8, 0, 0, 0,
+		remind.LosAngeles))
+	prev := remind.SetClock(clk)
+	defer remind.SetClock(prev)
+
+	db := newTestServer(t)
+	from := "+15555550100"
+
+	assert.Contains(t, textIn(t, db, from, "List")[0], "You have no reminders")
+
+	textIn(t, db, from, "Remind me to buy milk at 6pm tomorrow")
+	textIn(t, db, from, "Remind me to stretch @ 10am daily")
+	textIn(t, db, from, "Remind me to pay rent on the 1st of every month at 9am")
+	textIn(t, db, "+15555550199", "Remind me to feed the cat at 7am daily")
+	textIn(t, db, from, "Stop 1")
+
+	for _, body := range []string{"list", "My reminders", "list my reminders",
+		"Show my reminders please", "reminders?"} {
+		replies := textIn(t, db, from, body)
+		if !assert.Len(t, replies, 1, body) {
+			continue
+		}
+		lines := strings.Split(replies[0], "\n")
+		if assert.Len(t, lines, 2, body) {
+			assert.True(t, strings.HasPrefix(lines[0], "#2 Stretch: "), lines[0])
+			assert.True(t, strings.HasSuffix(lines[0], "every 24h0m0s"), lines[0])
+			assert.True(t, strings.HasPrefix(lines[1], "#3 Pay rent: "), lines[1])
+			assert.Contains(t, lines[1], " 9:00am ", lines[1])
+		}
+	}
+
+	// Times are in the user's timezone
+	textIn(t, db, from, "Set my timezone to America/New_York")
+	replies := textIn(t, db, from, "list")
+	if assert.Len(t, replies, 1) {
+		assert.Contains(t, replies[0], "1:00pm EDT")
+	}
+
+	for _, body := range []string{"list everything", "Remind me to list" +
+		" my reminders at 9am"} {
+		assert.False(t, regexList.MatchString(body), body)
+	}
+}
+
+func TestListFailed(t *testing.T) {
+	db := newTestServer(t)
+	from := "+15555550100"
+
+	textIn(t, db, from, "Remind me to stretch @ 10am daily")
+	id, err := remind.ReminderID(db, from, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+	r, err := remind.GetReminder(db, id)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Sent through an outbox that gives up right away
+	sent := twilhelp.NewFakeSender()
+	sent.FailWith(errors.New("Invalid number"))
+	outbox := remind.NewOutbox(db, sent)
+	outbox.MaxAttempts = 1
+	_, err = r.Send(context.Background(), outbox)
+	assert.NoError(t, err)
+	outbox.Start()
+	for {
+		pending, err := outbox.Pending()
+		if !assert.NoError(t, err) || len(pending) == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	outbox.Stop()
+
+	replies := textIn(t, db, from, "list")
+	if assert.Len(t, replies, 1) {
+		assert.True(t, strings.HasSuffix(replies[0], "every 24h0m0s (1 failed"+
+			" to send)"), replies[0])
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	assert.Equal(t, []string{"a\nb"}, paginate([]string{"a", "b"}, 160, 70))
+
+	lines := []string{
+		strings.Repeat("a", 10),
+		strings.Repeat("b", 10),
+		strings.Repeat("c", 15),
+		strings.Repeat("d", 40),
+	}
+	msgs := paginate(lines, 30, 30)
+	assert.Equal(t, []string{
+		"(1/3)\naaaaaaaaaa\nbbbbbbbbbb",
+		"(2/3)\nccccccccccccccc",
+		"(3/3)\n" + strings.Repeat("d", 19) + "...",
+	}, msgs)
+	for _, msg := range msgs {
+		assert.True(t, len(msg) <= 30, msg)
+	}
+}
+
+func TestPaginateUCS2(t *testing.T) {
+	// 100 GSM-7 characters fit in one segment, even accented ones
+	gsm := strings.Repeat("é", 50) + strings.Repeat("a", 50)
+	assert.Equal(t, []string{gsm}, paginate([]string{gsm}, 160, 70))
+
+	// ...but an emoji makes the whole page UCS-2
+	lines := []string{strings.Repeat("a", 60), "Water plants 🌱"}
+	msgs := paginate(lines, 160, 70)
+	assert.Equal(t, []string{"(1/2)\n" + lines[0], "(2/2)\n" + lines[1]},
+		msgs)
+	for _, msg := range msgs {
+		size, max := smsSize(msg, 160, 70)
+		assert.True(t, size <= max, msg)
+	}
+
+	// Too long for a UCS-2 segment on its own, so truncated to one
+	msgs = paginate([]string{strings.Repeat("ã", 80)}, 160, 70)
+	assert.Equal(t, []string{strings.Repeat("ã", 59) + "..."}, msgs)
+
+	// Extension characters take two
+	size, max := smsSize("{a}", 160, 70)
+	assert.Equal(t, 5, size)
+	assert.Equal(t, 160, max)
+	size, max = smsSize("a🌱", 160, 70)
+	assert.Equal(t, 3, size)
+	assert.Equal(t, 70, max)
+}
+
+func TestStopOwnReminders(t *testing.T) {
+	db := newTestServer(t)
+	alice, bob := "+15555550100", "+15555550101"
+
+	// Each is numbered among the sender's own reminders
+	replies := textIn(t, db, alice, "Remind me to buy milk at 6pm tomorrow")
+	assert.Contains(t, replies[0], "Reminder 1 successfully scheduled")
+	replies = textIn(t, db, bob, "Remind me to walk the dog @ 7am daily")
+	assert.Contains(t, replies[0], "Reminder 1 successfully scheduled")
+	replies = textIn(t, db, alice, "Remind me to stretch @ 10am daily")
+	assert.Contains(t, replies[0], "Reminder 2 successfully scheduled")
+
+	replies = textIn(t, db, bob, "Stop 2")
+	assert.Equal(t, []string{"Reminder 2 not found"}, replies)
+	assert.Equal(t, 3, scheduler.Len())
+
+	replies = textIn(t, db, alice, "delete reminder 1, 2, 99")
+	assert.Equal(t, []string{"Reminder 1 stopped\nReminder 2 stopped\n" +
+		"Reminder 99 not found"}, replies)
+	assert.Equal(t, 1, scheduler.Len())
+
+	replies = textIn(t, db, alice, "Stop 2")
+	assert.Equal(t, []string{"Reminder 2 was already stopped"}, replies)
+
+	replies = textIn(t, db, bob, "list")
+	assert.True(t, strings.HasPrefix(replies[0], "#1 Walk the dog: "))
+
+	replies = textIn(t, db, bob, "Stop #1")
+	assert.Equal(t, []string{"Reminder 1 stopped. Have an epic day!"}, replies)
+	assert.Equal(t, 0, scheduler.Len())
+}
+
+// startTestScheduler replaces the global scheduler with a running one
+// for db's reminders, sending with sent
+func startTestScheduler(t *testing.T, db *bolt.DB, sent twilhelp.Sender) {
+	rems, err := remind.GetAllReminders(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduler = remind.NewScheduler(db, sent, 1)
+	scheduler.Schedule(rems)
+	scheduler.Start()
+}
+
+func TestSnooze(t *testing.T) {
+	clk := remind.NewFakeClock(time.Date(2026, 6, 1, 8, 0, 0, 0,
+		remind.LosAngeles))
+	prev := remind.SetClock(clk)
+	defer remind.SetClock(prev)
+
+	db := newTestServer(t)
+	sent := twilhelp.NewFakeSender()
+	from := "+15555550100"
+
+	assert.Equal(t, []string{"Error snoozing: " + remind.ErrNothingSent.Error()},
+		textIn(t, db, from, "snooze"))
+
+	textIn(t, db, from, "Remind me to stretch in 5 minutes")
+	startTestScheduler(t, db, sent)
+	clk.BlockUntil(1)
+	clk.Advance(5 * time.Minute)
+	_, ok := sent.WaitFor(1, 5*time.Second)
+	scheduler.Stop()
+	if !assert.True(t, ok) {
+		return
+	}
+
+	assert.Equal(t, []string{"Snoozed Reminder 1 until 8:20am PDT"},
+		textIn(t, db, from, "Snooze for 15 minutes"))
+	assert.Equal(t, []string{"Snoozed Reminder 1 until 8:35am PDT"},
+		textIn(t, db, from, "snooze 30"))
+	assert.Equal(t, []string{"Snoozed Reminder 1 until 8:15am PDT"},
+		textIn(t, db, from, "Later!"))
+
+	startTestScheduler(t, db, sent)
+	defer scheduler.Stop()
+	clk.BlockUntil(1)
+	clk.Advance(10 * time.Minute)
+	msgs, ok := sent.WaitFor(2, 5*time.Second)
+	if assert.True(t, ok) {
+		assert.Equal(t, "Reminder 1: Stretch", msgs[1].Body)
+	}
+
+	for _, body := range []string{"snooze please", "later today",
+		"Remind me to snooze at 1pm"} {
+		assert.False(t, regexSnooze.MatchString(body), body)
+	}
+}
+
+func TestParseUntilDone(t *testing.T) {
+	r, err := parseReminder(&remind.User{}, "Remind me to take my pills at 9am until done")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Take my pills", r.Description)
+		assert.Equal(t, remind.DefaultNagEvery, r.NagEvery)
+		assert.False(t, r.Repeats())
+	}
+
+	r, err = parseReminder(&remind.User{}, "Remind me to take my pills daily at 9am until I say done, every 20 minutes")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 24*time.Hour, r.Period)
+		assert.Equal(t, 20*time.Minute, r.NagEvery)
+	}
+
+	r, err = parseReminder(&remind.User{}, "Remind me to water the plants every 10 minutes until done")
+	if assert.NoError(t, err) {
+		assert.Equal(t, time.Duration(0), r.Period, "Sent again, not repeated")
+		assert.Equal(t, 10*time.Minute, r.NagEvery)
+	}
+
+	r, err = parseReminder(&remind.User{}, "Remind me to walk the dog at 6pm and nag me every 5 minutes")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Walk the dog", r.Description)
+		assert.Equal(t, 5*time.Minute, r.NagEvery)
+	}
+
+	for _, body := range []string{
+		"Remind me to stretch at 9am until done every minute",
+		"Remind me to stretch every 20 minutes until done every 30 minutes",
+		"Remind me to stretch at 9am until done until done",
+	} {
+		_, err = parseReminder(&remind.User{}, body)
+		assert.Error(t, err, body)
+	}
+}
+
+func TestDone(t *testing.T) {
+	clk := remind.NewFakeClock(time.Date(2026, 6, 1, 8, 0, 0, 0,
+		remind.LosAngeles))
+	prev := remind.SetClock(clk)
+	defer remind.SetClock(prev)
+
+	db := newTestServer(t)
+	sent := twilhelp.NewFakeSender()
+	from := "+15555550100"
+
+	assert.Equal(t, []string{remind.ErrNothingSent.Error()},
+		textIn(t, db, from, "done"))
+
+	textIn(t, db, from, "Remind me to take my pills in 5 minutes until done")
+	startTestScheduler(t, db, sent)
+	clk.BlockUntil(1)
+	clk.Advance(5 * time.Minute)
+	msgs, ok := sent.WaitFor(1, 5*time.Second)
+	scheduler.Stop()
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, `Reminder 1: Take my pills (reply "done 1" when done)`,
+		msgs[0].Body)
+
+	assert.Equal(t, []string{"Reminder 2 not found"},
+		textIn(t, db, from, "done 2"))
+	assert.Equal(t, []string{"Reminder 1 not found"},
+		textIn(t, db, "+15555550199", "Done 1"))
+	assert.Equal(t, []string{"Reminder 1 marked done. Nice work!"},
+		textIn(t, db, from, "Done!"))
+	assert.Equal(t, []string{"Reminder 1 isn't waiting for you to say it's done"},
+		textIn(t, db, from, "done #1"))
+
+	r, err := remind.GetReminder(db, 1)
+	if assert.NoError(t, err) && assert.Len(t, r.History, 1) {
+		assert.Nil(t, r.Pending)
+		assert.False(t, r.History[0].Acked.IsZero())
+	}
+	assert.Equal(t, 0, scheduler.Len())
+
+	for _, body := range []string{"done deal", "Remind me to stretch when done"} {
+		assert.False(t, regexDone.MatchString(body), body)
+	}
+}
+
+func TestEscalation(t *testing.T) {
+	r, err := parseReminder(&remind.User{}, "Remind me to take my pills daily at 9am until done, escalate to (555) 555-0111 after 45 minutes as Mom")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Take my pills", r.Description)
+		assert.Equal(t, []string{"+15555550111"}, r.EscalateTo)
+		assert.Equal(t, 45*time.Minute, r.EscalateAfter)
+		assert.Equal(t, "Mom", r.RecipientName)
+		assert.Equal(t, "Mom hasn't confirmed: Take my pills",
+			r.EscalationMessage())
+	}
+
+	r, err = parseReminder(&remind.User{}, "Remind me to take my pills at 9am until done and escalate to +1 555 555 0111 and escalate to +15555550112")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"+15555550111", "+15555550112"}, r.EscalateTo)
+		assert.True(t, r.MustAck())
+	}
+
+	r, err = parseReminder(&remind.User{}, "Remind me to take my pills at 9am until done, escalate to 5555550111, escalate to 5555550112, escalate to 5555550113, escalate to 555-555-0111")
+	if assert.NoError(t, err) {
+		assert.Len(t, r.EscalateTo, remind.MaxEscalateTo)
+	}
+
+	// Telling someone something is part of what to do
+	for _, body := range []string{
+		"Remind me to pick up groceries and text 555-555-0111 at 5pm",
+		"Remind me to call the bank and tell 5555550111 at 5pm",
+	} {
+		r, err = parseReminder(&remind.User{}, body)
+		if assert.NoError(t, err, body) {
+			assert.Contains(t, r.Description, "555", body)
+			assert.Empty(t, r.EscalateTo, body)
+			assert.False(t, r.MustAck(), body)
+		}
+	}
+
+	for _, body := range []string{
+		"Remind me to take my pills hourly until done, escalate to 5555550111 after 2 hours",
+		"Remind me to take my pills at 9am until done, escalate to 5555550111 in 1 hour and escalate to 5555550112 in 2 hours",
+		// Too many to tell
+		"Remind me to take my pills at 9am until done, escalate to 5555550111, escalate to 5555550112, escalate to 5555550113, escalate to 5555550114",
+		// Nothing to say is done
+		"Remind me to take my pills at 9am, escalate to 5555550111",
+	} {
+		_, err = parseReminder(&remind.User{}, body)
+		assert.Error(t, err, body)
+	}
+
+	// Sent, then escalated when not done
+	clk := remind.NewFakeClock(time.Date(2026, 6, 1, 8, 0, 0, 0,
+		remind.LosAngeles))
+	prev := remind.SetClock(clk)
+	defer remind.SetClock(prev)
+
+	db := newTestServer(t)
+	sent := twilhelp.NewFakeSender()
+
+	textIn(t, db, "+15555550100", "Remind me to take my pills in 5 minutes and nag me every 2 hours and escalate to 5555550111 after 30 minutes as Mom")
+	textIn(t, db, "+15555550111", "YES")
+	startTestScheduler(t, db, sent)
+	defer scheduler.Stop()
+	for {
+		clk.Advance(time.Minute)
+		if _, ok := sent.WaitFor(2, 10*time.Millisecond); ok {
+			break
+		}
+		if clk.Now().After(time.Date(2026, 6, 1, 9, 0, 0, 0, remind.LosAngeles)) {
+			t.Fatal("Reminder never escalated")
+		}
+	}
+	msg := sent.Last()
+	assert.Equal(t, "+15555550111", msg.To)
+	assert.Equal(t, "Mom hasn't confirmed: Take my pills", msg.Body)
+}
+
+func TestEscalationConsent(t *testing.T) {
+	db := newTestServer(t)
+	sent := twilhelp.NewFakeSender()
+	scheduler = remind.NewScheduler(db, sent, 1)
+	from, contact := "+15555550100", "+15555550111"
+
+	// Asked once, however many reminders tell them
+	textIn(t, db, from, "Remind me to take my pills at 9am daily until done, escalate to 5555550111 as Mom")
+	textIn(t, db, from, "Remind me to walk the dog at 6pm daily until done, escalate to 5555550111")
+	msgs := sent.Sent()
+	if assert.Len(t, msgs, 1) {
+		assert.Equal(t, contact, msgs[0].To)
+		assert.Contains(t, msgs[0].Body, "Reply YES to get alerts from "+from)
+	}
+
+	consent := func() remind.Consent {
+		c, err := remind.GetConsent(db, contact, from)
+		assert.NoError(t, err)
+		return c
+	}
+	assert.Equal(t, remind.ConsentAsked, consent())
+
+	assert.Equal(t, []string{"You'll be texted when +15555550100 doesn't" +
+		" confirm their reminders. Reply STOP to stop."},
+		textIn(t, db, contact, "yes"))
+	assert.Equal(t, remind.ConsentGiven, consent())
+
+	assert.Equal(t, []string{"You won't be texted about +15555550100's" +
+		" reminders."}, textIn(t, db, contact, "STOP"))
+	assert.Equal(t, remind.ConsentRefused, consent())
+
+	// Changed their mind
+	textIn(t, db, contact, "Yes +1 (555) 555-0100")
+	assert.Equal(t, remind.ConsentGiven, consent())
+	textIn(t, db, contact, "stop alerts from 555-555-0100")
+	assert.Equal(t, remind.ConsentRefused, consent())
+
+	// Only about those who asked
+	assert.Equal(t, []string{"Reminder 12 not found"},
+		textIn(t, db, contact, "stop 12"))
+	assert.NotContains(t, textIn(t, db, "+15555550112", "YES")[0],
+		"You'll be texted")
+	assert.Equal(t, remind.ConsentRefused, consent())
+}
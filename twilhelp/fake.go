@@ -0,0 +1,110 @@
+package twilhelp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Message is a text a FakeSender was asked to send
+type Message struct {
+	ID   MessageID
+	To   string
+	Body string
+}
+
+// FakeSender is a Sender that records messages rather than sending
+// them, for tests
+type FakeSender struct {
+	mu      sync.Mutex
+	sent    []Message
+	err     error
+	changed chan struct{} // Closed, then replaced, on each send
+}
+
+func NewFakeSender() *FakeSender {
+	return &FakeSender{changed: make(chan struct{})}
+}
+
+func (f *FakeSender) Send(ctx context.Context, to, body string) (MessageID, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return "", f.err
+	}
+
+	msg := Message{
+		ID:   MessageID(fmt.Sprintf("fake%d", len(f.sent)+1)),
+		To:   to,
+		Body: body,
+	}
+	f.sent = append(f.sent, msg)
+
+	close(f.changed)
+	f.changed = make(chan struct{})
+
+	return msg.ID, nil
+}
+
+// Sent returns the messages sent so far, oldest first
+func (f *FakeSender) Sent() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Message(nil), f.sent...)
+}
+
+// Last returns the most recently sent message, or the zero Message if
+// none have been sent
+func (f *FakeSender) Last() Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.sent) == 0 {
+		return Message{}
+	}
+	return f.sent[len(f.sent)-1]
+}
+
+// Reset forgets the messages sent so far
+func (f *FakeSender) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = nil
+}
+
+// FailWith makes Send return err without sending, until called again
+// with nil
+func (f *FakeSender) FailWith(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+// WaitFor waits up to timeout for n messages to have been sent,
+// returning them and whether there were that many in time
+func (f *FakeSender) WaitFor(n int, timeout time.Duration) ([]Message, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		f.mu.Lock()
+		sent := append([]Message(nil), f.sent...)
+		changed := f.changed
+		f.mu.Unlock()
+
+		if len(sent) >= n {
+			return sent, true
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return sent, false
+		}
+	}
+}
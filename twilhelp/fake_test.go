@@ -0,0 +1,46 @@
+package twilhelp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeSender(t *testing.T) {
+	var s Sender = NewFakeSender()
+	f := s.(*FakeSender)
+	ctx := context.Background()
+
+	id, err := s.Send(ctx, "+15555550100", "Hello")
+	assert.NoError(t, err)
+	assert.Equal(t, MessageID("fake1"), id)
+
+	errDown := errors.New("Twilio is down")
+	f.FailWith(errDown)
+	_, err = s.Send(ctx, "+15555550100", "Lost")
+	assert.Equal(t, errDown, err)
+	f.FailWith(nil)
+
+	go s.Send(ctx, "+15555550101", "World")
+	sent, ok := f.WaitFor(2, 5*time.Second)
+	if assert.True(t, ok) {
+		assert.Equal(t, []Message{
+			{"fake1", "+15555550100", "Hello"},
+			{"fake2", "+15555550101", "World"},
+		}, sent)
+	}
+	assert.Equal(t, "World", f.Last().Body)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = s.Send(cancelled, "+15555550100", "Too late")
+	assert.Equal(t, context.Canceled, err)
+
+	f.Reset()
+	assert.Len(t, f.Sent(), 0)
+	_, ok = f.WaitFor(1, time.Millisecond)
+	assert.False(t, ok)
+}
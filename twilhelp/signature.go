@@ -0,0 +1,90 @@
+package twilhelp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+// SignatureHeader is the header Twilio signs its webhook requests with
+const SignatureHeader = "X-Twilio-Signature"
+
+// Signature returns what Twilio would sign a request to fullURL that
+// POSTs params with, given the account's auth token: the base64 of the
+// HMAC-SHA1 of fullURL followed by each param's name and value, sorted
+// by name.
+//
+// See https://www.twilio.com/docs/usage/security#validating-requests
+func Signature(authToken, fullURL string, params url.Values) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(fullURL))
+	for _, name := range names {
+		values := append([]string(nil), params[name]...)
+		sort.Strings(values)
+		for _, v := range values {
+			mac.Write([]byte(name + v))
+		}
+	}
+
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ValidSignature reports whether signature is Twilio's for a request
+// to fullURL that POSTs params
+func ValidSignature(authToken, fullURL string, params url.Values, signature string) bool {
+	if authToken == "" || signature == "" {
+		return false
+	}
+	want := Signature(authToken, fullURL, params)
+	return hmac.Equal([]byte(want), []byte(signature))
+}
+
+// RequireSignature returns middleware that responds 403 Forbidden to
+// requests not signed by Twilio with authToken. baseURL is the
+// scheme://host Twilio makes requests to, if a proxy in between means
+// it can't be worked out from the request itself.
+func RequireSignature(authToken, baseURL string) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if err := req.ParseForm(); err != nil {
+			log.Printf("Error parsing form to check Twilio signature: %v\n", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+
+		fullURL := requestURL(req, baseURL)
+		if !ValidSignature(authToken, fullURL, req.PostForm,
+			req.Header.Get(SignatureHeader)) {
+			log.Printf("Rejecting request to %v with invalid Twilio"+
+				" signature\n", fullURL)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+	}
+}
+
+// requestURL returns the full URL req was made to, starting with
+// baseURL if given
+func requestURL(req *http.Request, baseURL string) string {
+	if baseURL != "" {
+		return baseURL + req.URL.RequestURI()
+	}
+
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	if proto := req.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + req.Host + req.URL.RequestURI()
+}
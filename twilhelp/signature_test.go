@@ -0,0 +1,102 @@
+package twilhelp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// From Twilio's docs and helper libraries
+const (
+	docsToken     = "12345"
+	docsURL       = "https://mycompany.com/myapp.php?foo=1&bar=2"
+	docsSignature = "RSOYDt4T1cUTdK1PDd93/VVr8B8="
+)
+
+var docsParams = url.Values{
+	"CallSid": {"CA1234567890ABCDE"},
+	"Caller":  {"+14158675309"},
+	"Digits":  {"1234"},
+	"From":    {"+14158675309"},
+	"To":      {"+18005551212"},
+}
+
+func TestSignature(t *testing.T) {
+	assert.Equal(t, docsSignature, Signature(docsToken, docsURL, docsParams))
+
+	assert.True(t, ValidSignature(docsToken, docsURL, docsParams, docsSignature))
+	assert.False(t, ValidSignature("54321", docsURL, docsParams, docsSignature))
+	assert.False(t, ValidSignature(docsToken, docsURL+"&baz=3", docsParams,
+		docsSignature))
+	assert.False(t, ValidSignature(docsToken, docsURL, docsParams, ""))
+	assert.False(t, ValidSignature("", docsURL, docsParams,
+		Signature("", docsURL, docsParams)))
+
+	tampered := url.Values{}
+	for k, v := range docsParams {
+		tampered[k] = v
+	}
+	tampered.Set("From", "+15555550100")
+	assert.False(t, ValidSignature(docsToken, docsURL, tampered, docsSignature))
+}
+
+func TestRequireSignature(t *testing.T) {
+	form := url.Values{"From": {"+15555550100"}, "Body": {"Stop 1"}}
+	const smsURL = "https://reminders.example.com/sms"
+
+	tests := []struct {
+		name      string
+		baseURL   string
+		host      string
+		header    http.Header
+		signature string
+		code      int
+	}{
+		{"Valid", "", "reminders.example.com",
+			http.Header{"X-Forwarded-Proto": {"https"}},
+			Signature(docsToken, smsURL, form), http.StatusOK},
+		{"Valid, given the base URL", "https://reminders.example.com",
+			"localhost:8080", nil,
+			Signature(docsToken, smsURL, form), http.StatusOK},
+		{"Signed for another URL", "", "localhost:8080", nil,
+			Signature(docsToken, smsURL, form), http.StatusForbidden},
+		{"Signed with another token", "https://reminders.example.com", "",
+			nil, Signature("54321", smsURL, form), http.StatusForbidden},
+		{"Unsigned", "https://reminders.example.com", "", nil, "",
+			http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/sms",
+			strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if test.host != "" {
+			req.Host = test.host
+		}
+		for k, v := range test.header {
+			req.Header[k] = v
+		}
+		if test.signature != "" {
+			req.Header.Set(SignatureHeader, test.signature)
+		}
+
+		w := httptest.NewRecorder()
+		RequireSignature(docsToken, test.baseURL)(w, req)
+		assert.Equal(t, test.code, w.Code, test.name)
+
+		// The form can still be read by the next handler
+		assert.Equal(t, "Stop 1", req.FormValue("Body"), test.name)
+	}
+
+	// Without a token, nothing is trusted
+	req := httptest.NewRequest("POST", smsURL, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set(SignatureHeader, Signature("", smsURL, form))
+	w := httptest.NewRecorder()
+	RequireSignature("", "https://reminders.example.com")(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
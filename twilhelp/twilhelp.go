@@ -4,6 +4,7 @@
 package twilhelp
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -18,7 +19,9 @@ var (
 	TwilioKey     = os.Getenv("TWILIO_KEY")
 	FromNumber    = os.Getenv("FROM_NUMBER")
 
-	tc = twilio.NewClient(TwilioAccount, TwilioKey, nil)
+	// DefaultSender sends from FromNumber using the Twilio account
+	// given by the env vars above
+	DefaultSender *Twilio
 )
 
 func init() {
@@ -35,19 +38,58 @@ func init() {
 	if len(FromNumber) == 10 {
 		FromNumber = "+1" + FromNumber
 	}
+
+	DefaultSender = NewTwilio(TwilioAccount, TwilioKey, FromNumber)
 }
 
-func SendSMS(toNumberOrig, msg string) error {
-	toNumber := cleanNumber(toNumberOrig)
+// MessageID identifies a sent message, e.g. by its Twilio SID
+type MessageID string
+
+// Sender sends text messages
+type Sender interface {
+	Send(ctx context.Context, to, body string) (MessageID, error)
+}
+
+// Twilio is a Sender that texts from From via a Twilio account
+type Twilio struct {
+	From   string
+	client *twilio.Client
+}
+
+// NewTwilio returns a Twilio that texts from the phone number from
+func NewTwilio(account, key, from string) *Twilio {
+	return &Twilio{
+		From:   from,
+		client: twilio.NewClient(account, key, nil),
+	}
+}
+
+func (t *Twilio) Send(ctx context.Context, toNumberOrig, body string) (MessageID, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	toNumber := CleanNumber(toNumberOrig)
 	fmt.Printf("Cleaned: %s => %s\n", toNumberOrig, toNumber)
-	params := twilio.MessageParams{Body: msg}
-	_, _, err := tc.Messages.Send(FromNumber, toNumber, params)
+	params := twilio.MessageParams{Body: body}
+	msg, _, err := t.client.Messages.Send(t.From, toNumber, params)
+	if err != nil {
+		return "", err
+	}
+	return MessageID(msg.Sid), nil
+}
+
+// SendSMS texts msg to toNumberOrig using DefaultSender
+func SendSMS(toNumberOrig, msg string) error {
+	_, err := DefaultSender.Send(context.Background(), toNumberOrig, msg)
 	return err
 }
 
 var reNumber = regexp.MustCompile(`\d+`)
 
-func cleanNumber(toNumberOrig string) string {
+// CleanNumber returns toNumberOrig as Twilio wants it, e.g.
+// +15555550100, assuming 10-digit numbers are North American
+func CleanNumber(toNumberOrig string) string {
 	digits := reNumber.FindAllString(toNumberOrig, -1)
 	num := strings.Join(digits, "")
 	if len(num) == 10 {
@@ -22,7 +22,7 @@ func TestCleanNumber(t *testing.T) {
 	}
 
 	for _, tt := range cleanTests {
-		got := cleanNumber(tt.orig)
+		got := CleanNumber(tt.orig)
 		assert.Equal(t, tt.cleaned, got)
 	}
 }